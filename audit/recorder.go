@@ -0,0 +1,56 @@
+// Package audit records security events together with the client that caused them.
+package audit
+
+import (
+	"context"
+	"server/logging"
+	"server/models"
+	"server/repositories"
+)
+
+// Client struct holds the information about the client that sent the request.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// clientKey is the key of [Client] in the context.
+type clientKey struct{}
+
+// WithClient will return a copy of the context carrying the client.
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext will return the client carried by the context.
+// Zero client is returned if the context doesn't carry one.
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(clientKey{}).(Client)
+	return client
+}
+
+// Recorder interface records security events.
+type Recorder interface {
+	// Record will save the event with the client of the context. Failing to record
+	// the event doesn't fail the action, so the error is only logged.
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+// RepositoryRecorder is implementation of [Recorder] that saves events to [repositories.AuditRepository].
+type RepositoryRecorder struct {
+	auditRepository repositories.AuditRepository
+}
+
+func (r *RepositoryRecorder) Record(ctx context.Context, event models.AuditEvent) {
+	client := ClientFromContext(ctx)
+	event.IP = client.IP
+	event.UserAgent = client.UserAgent
+
+	if err := r.auditRepository.AddEvent(ctx, event); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error recording audit event", "type", event.Type, "error", err)
+	}
+}
+
+func NewRepositoryRecorder(auditRepository repositories.AuditRepository) *RepositoryRecorder {
+	return &RepositoryRecorder{auditRepository}
+}
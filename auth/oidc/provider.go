@@ -0,0 +1,267 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"io"
+	"net/http"
+	"net/url"
+	"server/config"
+	"strings"
+	"sync"
+)
+
+// Metadata struct holds the part of the discovery document used by the relying party.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// IDToken struct holds the claims of a validated ID token.
+type IDToken struct {
+	Email             string `json:"email"`
+	EmailVerified     Bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// Bool is a bool claim that also accepts "true" and "false" strings,
+// which some providers send for email_verified.
+type Bool bool
+
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = Bool(v)
+	case string:
+		*b = Bool(v == "true")
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// Provider is the OpenID Connect provider used to log in users.
+type Provider struct {
+	metadata     Metadata
+	clientId     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	client       *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]any
+}
+
+// Issuer will return the issuer url of the provider.
+func (p *Provider) Issuer() string {
+	return p.metadata.Issuer
+}
+
+// AuthCodeURL will return the url of the provider the user should be redirected to.
+// The nonce is included in the ID token and the challenge is the S256 PKCE challenge of the verifier.
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.clientId)
+	params.Set("redirect_uri", p.redirectURL)
+	params.Set("scope", strings.Join(p.scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.metadata.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// Exchange will exchange the authorization code for tokens and return the validated ID token.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*IDToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(p.clientId), url.QueryEscape(p.clientSecret))
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", response.StatusCode, body)
+	}
+
+	var tokenResponse struct {
+		IdToken string `json:"id_token"`
+	}
+	if err = json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, err
+	}
+	if tokenResponse.IdToken == "" {
+		return nil, errors.New("token response doesn't contain id_token")
+	}
+
+	return p.VerifyIDToken(ctx, tokenResponse.IdToken, nonce)
+}
+
+// VerifyIDToken will validate the signature of the ID token against the keys of the provider,
+// its issuer, audience, expiration and nonce.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawToken string, nonce string) (*IDToken, error) {
+	claims := &IDToken{}
+	_, err := jwt.ParseWithClaims(
+		rawToken,
+		claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.metadata.Issuer),
+		jwt.WithAudience(p.clientId),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.clientId {
+		return nil, errors.New("id token is authorized for another party")
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errors.New("id token nonce doesn't match")
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("id token doesn't contain subject")
+	}
+
+	return claims, nil
+}
+
+// key will return the key of the provider with the id. The keys are fetched again if the
+// key is not known, so keys rotated by the provider are picked up.
+func (p *Provider) key(ctx context.Context, kid string) (any, error) {
+	if key, ok := p.cachedKey(kid); ok {
+		return key, nil
+	}
+
+	keys, err := fetchKeys(ctx, p.client, p.metadata.JwksURI)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	if key, ok := p.cachedKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// cachedKey will return the cached key with the id. Tokens without key id can be verified
+// only if the provider has a single key.
+func (p *Provider) cachedKey(kid string) (any, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// getJSON will fetch the url and decode the JSON response into the value.
+func getJSON(ctx context.Context, client *http.Client, url string, value any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", url, response.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(value)
+}
+
+// NewProvider will fetch the discovery document of the issuer and create [Provider].
+func NewProvider(ctx context.Context, conf *config.OIDCConfig, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	issuer := strings.TrimSuffix(conf.Issuer, "/")
+	var metadata Metadata
+	if err := getJSON(ctx, client, issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("error fetching discovery document: %w", err)
+	}
+
+	// The issuer must match exactly, otherwise ID tokens of another issuer could be accepted.
+	if metadata.Issuer != conf.Issuer {
+		return nil, fmt.Errorf("discovery issuer %q doesn't match %q", metadata.Issuer, conf.Issuer)
+	}
+
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JwksURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	return &Provider{
+		metadata:     metadata,
+		clientId:     conf.ClientId,
+		clientSecret: conf.ClientSecret,
+		redirectURL:  conf.RedirectURL,
+		scopes:       conf.Scopes,
+		client:       client,
+		keys:         map[string]any{},
+	}, nil
+}
@@ -0,0 +1,176 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"server/auth/oidc/oidctest"
+	"server/config"
+	"testing"
+	"time"
+)
+
+var testIdentity = oidctest.Identity{
+	Subject:           "subject",
+	Email:             "user@example.com",
+	EmailVerified:     true,
+	PreferredUsername: "user",
+}
+
+// newTestProvider will start the mock provider and create [Provider] for it.
+func newTestProvider(t *testing.T) (*oidctest.Server, *Provider) {
+	server, err := oidctest.NewServer("client", "secret")
+	if err != nil {
+		t.Fatalf("Error starting mock provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+	server.SetIdentity(testIdentity)
+
+	provider, err := NewProvider(context.Background(), &config.OIDCConfig{
+		Issuer:       server.URL,
+		ClientId:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+		Scopes:       []string{"openid", "email"},
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("Error creating provider: %v", err)
+	}
+
+	return server, provider
+}
+
+// authorize will follow the redirect to the mock provider and return the code and state of the callback.
+func authorize(t *testing.T, server *oidctest.Server, authURL string) (string, string) {
+	client := server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	response, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Error requesting authorization: %v", err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("Expected redirect, got %d", response.StatusCode)
+	}
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Error parsing redirect: %v", err)
+	}
+
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestProviderLogin(t *testing.T) {
+	server, provider := newTestProvider(t)
+
+	state, err := NewLoginState()
+	if err != nil {
+		t.Fatalf("Error creating login state: %v", err)
+	}
+
+	code, returnedState := authorize(t, server, provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier))
+	if returnedState != state.State {
+		t.Fatalf("Expected state %q, got %q", state.State, returnedState)
+	}
+
+	idToken, err := provider.Exchange(context.Background(), code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		t.Fatalf("Error exchanging code: %v", err)
+	}
+
+	if idToken.Subject != testIdentity.Subject || idToken.Email != testIdentity.Email || !bool(idToken.EmailVerified) {
+		t.Fatalf("Unexpected ID token claims: %+v", idToken)
+	}
+
+	// The code can be used only once.
+	if _, err = provider.Exchange(context.Background(), code, state.CodeVerifier, state.Nonce); err == nil {
+		t.Fatal("Expected error, because the code was already used")
+	}
+}
+
+func TestProviderRejectsWrongNonce(t *testing.T) {
+	server, provider := newTestProvider(t)
+
+	state, _ := NewLoginState()
+	code, _ := authorize(t, server, provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier))
+
+	if _, err := provider.Exchange(context.Background(), code, state.CodeVerifier, "other"); err == nil {
+		t.Fatal("Expected error, because the nonce doesn't match")
+	}
+}
+
+func TestProviderRejectsWrongAudience(t *testing.T) {
+	server, provider := newTestProvider(t)
+	server.Audience = "other-client"
+
+	state, _ := NewLoginState()
+	code, _ := authorize(t, server, provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier))
+
+	if _, err := provider.Exchange(context.Background(), code, state.CodeVerifier, state.Nonce); err == nil {
+		t.Fatal("Expected error, because the token is issued for another client")
+	}
+}
+
+func TestProviderRotatedKey(t *testing.T) {
+	server, provider := newTestProvider(t)
+
+	for i := 0; i < 2; i++ {
+		state, _ := NewLoginState()
+		code, _ := authorize(t, server, provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier))
+
+		if _, err := provider.Exchange(context.Background(), code, state.CodeVerifier, state.Nonce); err != nil {
+			t.Fatalf("Error exchanging code: %v", err)
+		}
+
+		if err := server.RotateKey(); err != nil {
+			t.Fatalf("Error rotating key: %v", err)
+		}
+	}
+}
+
+func TestNewProviderIssuerMismatch(t *testing.T) {
+	server, err := oidctest.NewServer("client", "secret")
+	if err != nil {
+		t.Fatalf("Error starting mock provider: %v", err)
+	}
+	defer server.Close()
+
+	_, err = NewProvider(context.Background(), &config.OIDCConfig{Issuer: server.URL + "/"}, server.Client())
+	if err == nil {
+		t.Fatal("Expected error, because the issuer doesn't match")
+	}
+}
+
+func TestSignState(t *testing.T) {
+	state, err := NewLoginState()
+	if err != nil {
+		t.Fatalf("Error creating login state: %v", err)
+	}
+
+	value, err := SignState(*state, []byte("secret"), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Error signing state: %v", err)
+	}
+
+	parsed, err := ParseState(value, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Error parsing state: %v", err)
+	}
+	if *parsed != *state {
+		t.Fatalf("Expected %+v, got %+v", state, parsed)
+	}
+
+	if _, err = ParseState(value, []byte("other")); err == nil {
+		t.Fatal("Expected error, because the secret doesn't match")
+	}
+
+	expired, _ := SignState(*state, []byte("secret"), time.Now().Add(-time.Minute))
+	if _, err = ParseState(expired, []byte("secret")); err == nil {
+		t.Fatal("Expected error, because the state is expired")
+	}
+}
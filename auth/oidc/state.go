@@ -0,0 +1,89 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+// LoginState struct holds the values that must be kept between the redirect to the provider and the callback.
+type LoginState struct {
+	// State protects the callback against cross site request forgery.
+	State string `json:"state"`
+	// Nonce binds the ID token to the login.
+	Nonce string `json:"nonce"`
+	// CodeVerifier is the PKCE verifier of the authorization code.
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// stateClaims struct holds the login state signed in the cookie.
+type stateClaims struct {
+	LoginState
+	jwt.RegisteredClaims
+}
+
+// NewLoginState will generate random state, nonce and code verifier.
+func NewLoginState() (*LoginState, error) {
+	values := make([]string, 3)
+	for i := range values {
+		bytes := make([]byte, 32)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(bytes)
+	}
+
+	return &LoginState{
+		State:        values[0],
+		Nonce:        values[1],
+		CodeVerifier: values[2],
+	}, nil
+}
+
+// stateKey will derive the key used to sign the state from the secret, so the state can't be
+// confused with other tokens signed with the secret.
+func stateKey(secret []byte) []byte {
+	sum := sha256.Sum256(append([]byte("oidc-state:"), secret...))
+	return sum[:]
+}
+
+// SignState will encode the state as a signed value that can be stored in a cookie.
+func SignState(state LoginState, secret []byte, exp time.Time) (string, error) {
+	claims := stateClaims{
+		LoginState: state,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(stateKey(secret))
+}
+
+// ParseState will verify the signed value created by [SignState] and return the state.
+func ParseState(value string, secret []byte) (*LoginState, error) {
+	claims := &stateClaims{}
+	_, err := jwt.ParseWithClaims(
+		value,
+		claims,
+		func(token *jwt.Token) (any, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return stateKey(secret), nil
+		},
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.State == "" || claims.Nonce == "" || claims.CodeVerifier == "" {
+		return nil, errors.New("invalid login state")
+	}
+
+	return &claims.LoginState, nil
+}
@@ -0,0 +1,35 @@
+package passwords
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// Bcrypt is implementation of [Algorithm] using bcrypt.
+// Note that bcrypt uses only the first 72 bytes of the password.
+type Bcrypt struct {
+	cost int
+}
+
+func (b *Bcrypt) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	return string(bytes), err
+}
+
+func (b *Bcrypt) Identify(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (b *Bcrypt) Verify(password, hash string) (bool, bool) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err != nil || cost != b.cost
+}
+
+func NewBcrypt(cost int) *Bcrypt {
+	return &Bcrypt{cost}
+}
@@ -0,0 +1,142 @@
+package passwords
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedChecker interface checks if a password appeared in a data breach.
+type BreachedChecker interface {
+	// IsBreached will return true if the password is in the breached passwords.
+	IsBreached(password string) (bool, error)
+}
+
+// hashPrefixLength is the length of the SHA-1 prefix used by the k-anonymity range files.
+const hashPrefixLength = 5
+
+// RangeChecker is implementation of [BreachedChecker] using the offline k-anonymity range files
+// of Have I Been Pwned. The path is either a directory with a file for every 5 characters
+// SHA-1 prefix (for example 21BD1 or 21BD1.txt) containing SUFFIX:COUNT lines,
+// or a single file with HASH:COUNT lines sorted by the hash.
+// The passwords are never stored or sent anywhere, only their SHA-1 hash is looked up.
+type RangeChecker struct {
+	path string
+}
+
+func (c *RangeChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return false, err
+	}
+
+	if info.IsDir() {
+		return c.searchRangeFile(hash[:hashPrefixLength], hash[hashPrefixLength:])
+	}
+
+	return c.searchSortedFile(hash, info.Size())
+}
+
+// searchRangeFile will search the suffix in the range file of the prefix.
+func (c *RangeChecker) searchRangeFile(prefix, suffix string) (bool, error) {
+	file, err := os.Open(filepath.Join(c.path, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(filepath.Join(c.path, prefix+".txt"))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(lineSuffix), suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// searchSortedFile will binary search the hash in a file with lines sorted by the hash.
+func (c *RangeChecker) searchSortedFile(hash string, size int64) (bool, error) {
+	file, err := os.Open(c.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	// The search finds the first line starting at or after an offset,
+	// so the range of offsets is narrowed until the line with the hash is found.
+	low, high := int64(0), size
+	for low < high {
+		middle := low + (high-low)/2
+		line, err := readLineAfter(file, middle)
+		if err != nil {
+			return false, err
+		}
+
+		// The end of the file is after every hash.
+		lineHash, _, _ := strings.Cut(line, ":")
+		lineHash = strings.ToUpper(strings.TrimSpace(lineHash))
+		switch {
+		case lineHash == hash:
+			return true, nil
+		case lineHash != "" && lineHash < hash:
+			low = middle + 1
+		default:
+			high = middle
+		}
+	}
+
+	// The first line can't be found after any offset, so it is checked separately.
+	line, err := readLineAfter(file, -1)
+	if err != nil {
+		return false, err
+	}
+	lineHash, _, _ := strings.Cut(line, ":")
+	return strings.EqualFold(strings.TrimSpace(lineHash), hash), nil
+}
+
+// readLineAfter will return the first complete line that starts after the offset.
+// The offset -1 returns the first line of the file. At the end of the file an empty line is returned.
+func readLineAfter(file *os.File, offset int64) (string, error) {
+	start := offset
+	if start < 0 {
+		start = 0
+	}
+
+	buffer := make([]byte, 256)
+	n, err := file.ReadAt(buffer, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	buffer = buffer[:n]
+
+	if offset >= 0 {
+		index := bytes.IndexByte(buffer, '\n')
+		if index < 0 {
+			return "", nil
+		}
+		buffer = buffer[index+1:]
+	}
+
+	line, _, _ := bytes.Cut(buffer, []byte("\n"))
+	return string(line), nil
+}
+
+func NewRangeChecker(path string) *RangeChecker {
+	return &RangeChecker{path}
+}
@@ -1,32 +1,101 @@
 package passwords
 
 import (
+	"strings"
 	"testing"
 )
 
-// TestHashPassword will check if the [HashPassword] function works.
+// testParams are small argon2id parameters, so the tests are fast.
+var testParams = Argon2idParams{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var hasher = NewHasher(NewArgon2id(testParams), NewBcrypt(4))
+
+// TestHashPassword will check if the [Hasher.HashPassword] function works.
 func TestHashPassword(t *testing.T) {
 	password := "password"
-	hash, err := HashPassword(password)
+	hash, err := hasher.HashPassword(password)
 	if err != nil {
 		t.Errorf("HashPassword() error = %v", err)
 	}
 
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("HashPassword() returned hash that is not PHC formatted argon2id: %v", hash)
+	}
+
 	t.Log(hash)
 }
 
-// TestVerifyPassword will check if the [VerifyPassword] function correctrly checks passwords.
+// TestVerifyPassword will check if the [Hasher.VerifyPassword] function correctrly checks passwords.
 func TestVerifyPassword(t *testing.T) {
 	password := "password"
-	hash, err := HashPassword(password)
+	hash, err := hasher.HashPassword(password)
 	if err != nil {
 		t.Errorf("HashPassword() error = %v", err)
 	}
 
 	t.Log(hash)
 
-	result := VerifyPassword(password, hash)
+	result, rehash := hasher.VerifyPassword(password, hash)
 	if !result {
 		t.Errorf("VerifyPassword() doen't return true when checking the same password")
 	}
+	if rehash {
+		t.Errorf("VerifyPassword() requested rehash of up to date hash")
+	}
+
+	result, _ = hasher.VerifyPassword("other password", hash)
+	if result {
+		t.Errorf("VerifyPassword() returned true when checking different password")
+	}
+}
+
+// TestVerifyPasswordRehash will check if hashes with outdated algorithm or parameters are detected.
+func TestVerifyPasswordRehash(t *testing.T) {
+	password := "password"
+	bcryptHash, err := NewBcrypt(4).Hash(password)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	result, rehash := hasher.VerifyPassword(password, bcryptHash)
+	if !result || !rehash {
+		t.Errorf("VerifyPassword() = %v, %v for bcrypt hash, expected true, true", result, rehash)
+	}
+
+	result, rehash = hasher.VerifyPassword("other password", bcryptHash)
+	if result || rehash {
+		t.Errorf("VerifyPassword() = %v, %v for wrong password, expected false, false", result, rehash)
+	}
+
+	oldParams := testParams
+	oldParams.Iterations = 2
+	oldHash, err := NewArgon2id(oldParams).Hash(password)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	result, rehash = hasher.VerifyPassword(password, oldHash)
+	if !result || !rehash {
+		t.Errorf("VerifyPassword() = %v, %v for outdated argon2id hash, expected true, true", result, rehash)
+	}
+}
+
+// TestHashLongPassword will check that passwords longer than 72 bytes are not truncated.
+func TestHashLongPassword(t *testing.T) {
+	password := strings.Repeat("a", 100)
+	hash, err := hasher.HashPassword(password)
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	result, _ := hasher.VerifyPassword(strings.Repeat("a", 72), hash)
+	if result {
+		t.Errorf("VerifyPassword() matched truncated password")
+	}
 }
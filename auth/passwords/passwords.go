@@ -1,15 +1,76 @@
 package passwords
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"fmt"
+	"server/config"
 )
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+// Algorithm interface hashes and verifies passwords with a single hashing algorithm.
+type Algorithm interface {
+	// Hash will return the encoded hash of the password.
+	Hash(password string) (string, error)
+
+	// Identify will return true if the hash was created with the algorithm.
+	Identify(hash string) bool
+
+	// Verify will check if the password matches the hash. If it matches outdated is true when
+	// the hash was created with other parameters than the current ones of the algorithm.
+	Verify(password, hash string) (match bool, outdated bool)
+}
+
+// Hasher hashes passwords with the preferred [Algorithm] and verifies hashes created with any known algorithm.
+type Hasher struct {
+	preferred  Algorithm
+	algorithms []Algorithm
 }
 
-func VerifyPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// HashPassword will hash the password with the preferred algorithm.
+func (h *Hasher) HashPassword(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+// VerifyPassword will check if the password matches the hash. If it matches rehash is true when
+// the hash was created with outdated algorithm or parameters and should be replaced by [Hasher.HashPassword].
+func (h *Hasher) VerifyPassword(password, hash string) (match bool, rehash bool) {
+	for _, algorithm := range h.algorithms {
+		if !algorithm.Identify(hash) {
+			continue
+		}
+
+		match, outdated := algorithm.Verify(password, hash)
+		return match, match && (outdated || algorithm != h.preferred)
+	}
+
+	return false, false
+}
+
+// NewHasher will create [Hasher] that hashes with the preferred algorithm
+// and can also verify hashes of the other algorithms.
+func NewHasher(preferred Algorithm, others ...Algorithm) *Hasher {
+	return &Hasher{
+		preferred:  preferred,
+		algorithms: append([]Algorithm{preferred}, others...),
+	}
+}
+
+// NewHasherFromConfig will create [Hasher] with the algorithm selected by the configuration.
+// Hashes of the other algorithms can still be verified, so they are upgraded on login.
+func NewHasherFromConfig(conf *config.PasswordConfig) (*Hasher, error) {
+	argon2id := NewArgon2id(Argon2idParams{
+		Memory:      conf.Argon2Memory,
+		Iterations:  conf.Argon2Iterations,
+		Parallelism: conf.Argon2Parallelism,
+		SaltLength:  conf.Argon2SaltLength,
+		KeyLength:   conf.Argon2KeyLength,
+	})
+	bcrypt := NewBcrypt(conf.BcryptCost)
+
+	switch conf.Algorithm {
+	case config.Argon2idAlgorithm:
+		return NewHasher(argon2id, bcrypt), nil
+	case config.BcryptAlgorithm:
+		return NewHasher(bcrypt, argon2id), nil
+	default:
+		return nil, fmt.Errorf("unknown password algorithm: %s", conf.Algorithm)
+	}
 }
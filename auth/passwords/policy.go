@@ -0,0 +1,116 @@
+package passwords
+
+import (
+	"fmt"
+	"server/config"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy struct checks if passwords meet the configured requirements.
+type Policy struct {
+	conf     config.PasswordPolicyConfig
+	breached BreachedChecker
+}
+
+// Check will return all requirements the password doesn't meet. If the password is valid
+// the result is empty. The username and email are used to check that the password doesn't contain them.
+func (p *Policy) Check(password, username, email string) ([]string, error) {
+	violations := make([]string, 0)
+
+	length := utf8.RuneCountInString(password)
+	if length < p.conf.MinLength || length > p.conf.MaxLength {
+		violations = append(violations, fmt.Sprintf("Password must be between %d and %d characters", p.conf.MinLength, p.conf.MaxLength))
+	} else if p.conf.MaxBytes > 0 && len(password) > p.conf.MaxBytes {
+		violations = append(violations, fmt.Sprintf("Password must not be longer than %d bytes, characters outside ASCII take up to 4 bytes", p.conf.MaxBytes))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			hasSpecial = true
+		}
+	}
+
+	if p.conf.RequireUpper && !hasUpper {
+		violations = append(violations, "Password must contain at least one capital letter")
+	}
+	if p.conf.RequireLower && !hasLower {
+		violations = append(violations, "Password must contain at least one small letter")
+	}
+	if p.conf.RequireDigit && !hasDigit {
+		violations = append(violations, "Password must contain at least one number")
+	}
+	if p.conf.RequireSpecial && !hasSpecial {
+		violations = append(violations, "Password must contain at least one special character")
+	}
+
+	if p.conf.MaxRepeats > 0 && maxRepeats(password) > p.conf.MaxRepeats {
+		violations = append(violations, fmt.Sprintf("Password cannot repeat the same character more than %d times in a row", p.conf.MaxRepeats))
+	}
+
+	if p.conf.ForbidUserInfo && containsUserInfo(password, username, email) {
+		violations = append(violations, "Password cannot contain the username or the email")
+	}
+
+	// The breached check is the slowest, so it is done only for otherwise valid passwords.
+	if len(violations) == 0 && p.breached != nil {
+		breached, err := p.breached.IsBreached(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			violations = append(violations, "Password appeared in a data breach and cannot be used")
+		}
+	}
+
+	return violations, nil
+}
+
+// maxRepeats will return the length of the longest run of the same character.
+func maxRepeats(password string) int {
+	longest, current := 0, 0
+	var previous rune
+	for i, r := range []rune(password) {
+		if i > 0 && r == previous {
+			current++
+		} else {
+			current = 1
+		}
+		previous = r
+		longest = max(longest, current)
+	}
+
+	return longest
+}
+
+// containsUserInfo will return true if the password contains the username,
+// the email or the local part of the email ignoring the case.
+func containsUserInfo(password, username, email string) bool {
+	password = strings.ToLower(password)
+	localPart, _, _ := strings.Cut(email, "@")
+
+	// Very short values are ignored, because they would forbid too many passwords.
+	for _, value := range []string{username, email, localPart} {
+		if utf8.RuneCountInString(value) >= 3 && strings.Contains(password, strings.ToLower(value)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewPolicy(conf *config.PasswordPolicyConfig, breached BreachedChecker) *Policy {
+	return &Policy{
+		conf:     *conf,
+		breached: breached,
+	}
+}
@@ -0,0 +1,146 @@
+package passwords
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"server/config"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var policyConfig = config.PasswordPolicyConfig{
+	MinLength:      8,
+	MaxLength:      40,
+	RequireUpper:   true,
+	RequireLower:   true,
+	RequireDigit:   true,
+	RequireSpecial: true,
+	MaxRepeats:     3,
+	ForbidUserInfo: true,
+}
+
+// TestPolicyCheck will check if the [Policy.Check] returns all violations at once.
+func TestPolicyCheck(t *testing.T) {
+	policy := NewPolicy(&policyConfig, nil)
+	tests := []struct {
+		password   string
+		violations int
+	}{
+		{"Password_123", 0},
+		{"pass", 4},
+		{"password", 3},
+		{"PASSWORD_123", 1},
+		{"Paaaassword_123", 1},
+		{"Someone_123", 1},
+		{strings.Repeat("Ab1_", 11), 1},
+	}
+
+	for _, test := range tests {
+		violations, err := policy.Check(test.password, "someone", "someone@example.com")
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+
+		if len(violations) != test.violations {
+			t.Errorf("Check(%q) = %v, expected %d violations", test.password, violations, test.violations)
+		}
+	}
+}
+
+func TestPolicyCheckMaxBytes(t *testing.T) {
+	conf := policyConfig
+	conf.MaxLength = 128
+	conf.MaxBytes = config.BcryptMaxBytes
+	policy := NewPolicy(&conf, nil)
+
+	// 40 characters, but 80 bytes in UTF-8.
+	password := "Ab1_" + strings.Repeat("äö", 18)
+	violations, err := policy.Check(password, "someone", "someone@example.com")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(violations) != 1 {
+		t.Fatalf("Check(%q) = %v, expected the byte length violation", password, violations)
+	}
+
+	if _, err = NewBcrypt(4).Hash(password[:config.BcryptMaxBytes]); err != nil {
+		t.Fatalf("Expected bcrypt to hash a password of the maximal length, got %v", err)
+	}
+}
+
+// sha1Hex will return the upper case SHA-1 of the password like in the breached passwords files.
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestRangeCheckerDirectory(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("Password_123")
+	content := "0000000000000000000000000000000000A:1\n" + hash[5:] + ":42\n"
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(content), 0600); err != nil {
+		t.Fatalf("Error writing range file: %v", err)
+	}
+
+	checker := NewRangeChecker(dir)
+	for password, expected := range map[string]bool{"Password_123": true, "Password_456": false} {
+		result, err := checker.IsBreached(password)
+		if err != nil {
+			t.Fatalf("IsBreached() error = %v", err)
+		}
+		if result != expected {
+			t.Errorf("IsBreached(%q) = %v, expected %v", password, result, expected)
+		}
+	}
+}
+
+func TestRangeCheckerSortedFile(t *testing.T) {
+	breached := []string{"Password_1", "Password_2", "Password_3", "Password_4", "Password_5"}
+	hashes := make([]string, 0, len(breached))
+	for _, password := range breached {
+		hashes = append(hashes, sha1Hex(password))
+	}
+	slices.Sort(hashes)
+
+	var content strings.Builder
+	for _, hash := range hashes {
+		content.WriteString(hash + ":7\n")
+	}
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(content.String()), 0600); err != nil {
+		t.Fatalf("Error writing breached file: %v", err)
+	}
+
+	checker := NewRangeChecker(path)
+	for _, password := range breached {
+		result, err := checker.IsBreached(password)
+		if err != nil {
+			t.Fatalf("IsBreached() error = %v", err)
+		}
+		if !result {
+			t.Errorf("IsBreached(%q) = false, expected true", password)
+		}
+	}
+
+	result, err := checker.IsBreached("Password_6")
+	if err != nil {
+		t.Fatalf("IsBreached() error = %v", err)
+	}
+	if result {
+		t.Error("IsBreached() = true for password that is not breached")
+	}
+
+	// The policy should report breached passwords as violation.
+	policy := NewPolicy(&policyConfig, checker)
+	violations, err := policy.Check("Password_1", "someone", "someone@example.com")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(violations) != 1 {
+		t.Errorf("Check() = %v, expected breached violation", violations)
+	}
+}
@@ -33,9 +33,51 @@ const JWTClaimsKey = "claims"
 // Token struct holds token data.
 type Token struct {
 	TokenType TokenType `json:"token_type"`
+	// ReadOnly is true if the access token can only be used to read data.
+	ReadOnly bool `json:"read_only,omitempty"`
+	// SessionId is the id of the refresh token the access token was created with.
+	SessionId string `json:"sid,omitempty"`
+	// Role is the role of the user.
+	Role string `json:"role,omitempty"`
+	// ClientId is the id of the oauth client the access token was issued to. It is empty for first party tokens.
+	ClientId string `json:"client_id,omitempty"`
+	// Scope is the space separated list of scopes granted to the oauth client.
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// HasScope will return true if the token grants the scope.
+// First party tokens are not limited by scopes.
+func (t *Token) HasScope(scope string) bool {
+	if t.ClientId == "" {
+		return true
+	}
+
+	for _, s := range strings.Fields(t.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AccessClaims struct holds the user data carried by an access token.
+type AccessClaims struct {
+	// UserId is the id of the user set as subject of the token.
+	UserId int
+	// SessionId is the id of the refresh token created together with the access token.
+	SessionId uuid.UUID
+	// Role is the role of the user.
+	Role string
+	// ReadOnly is true if the access token can only be used to read data.
+	ReadOnly bool
+	// ClientId is the id of the oauth client the token is issued to. It is empty for first party tokens.
+	ClientId string
+	// Scopes are the scopes granted to the oauth client.
+	Scopes []string
+}
+
 // JWTAuthenticator used to authenticate user with JWT.
 type JWTAuthenticator struct {
 	secret []byte
@@ -59,11 +101,16 @@ func (a *JWTAuthenticator) CreateRefreshToken(tokenID uuid.UUID, exp time.Time)
 }
 
 // CreateAccessToken will create a new [Token] with set type of [AccessTokenType]
-func (a *JWTAuthenticator) CreateAccessToken(userId int, exp time.Time) (string, error) {
+func (a *JWTAuthenticator) CreateAccessToken(claims AccessClaims, exp time.Time) (string, error) {
 	token := Token{
 		TokenType: AccessTokenType,
+		ReadOnly:  claims.ReadOnly,
+		SessionId: claims.SessionId.String(),
+		Role:      claims.Role,
+		ClientId:  claims.ClientId,
+		Scope:     strings.Join(claims.Scopes, " "),
 		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   strconv.Itoa(userId),
+			Subject:   strconv.Itoa(claims.UserId),
 			ExpiresAt: jwt.NewNumericDate(exp),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    a.issuer,
@@ -100,12 +147,14 @@ func (a *JWTAuthenticator) Middleware(tokenType TokenType) fiber.Handler {
 		header := c.Get("Authorization")
 		header = strings.TrimPrefix(header, "Bearer ")
 		if len(header) == 0 {
-			return c.Status(fiber.StatusUnauthorized).JSON(utils.InvalidTokenErrorResponse())
+			utils.HandleErrorResponse(c, utils.InvalidTokenErrorResponse())
+			return nil
 		}
 
 		claims, err := a.VerifyToken(header, tokenType)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(utils.InvalidTokenErrorResponse())
+			utils.HandleErrorResponse(c, utils.InvalidTokenErrorResponse())
+			return nil
 		}
 
 		c.Locals(JWTClaimsKey, claims)
@@ -113,6 +162,88 @@ func (a *JWTAuthenticator) Middleware(tokenType TokenType) fiber.Handler {
 	}
 }
 
+// WriteAccessMiddleware will reject requests that modify data if the access token is read only.
+// It must be used after [JWTAuthenticator.Middleware].
+func (a *JWTAuthenticator) WriteAccessMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals(JWTClaimsKey).(*Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InvalidTokenErrorResponse())
+			return nil
+		}
+
+		if claims.ReadOnly && c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			utils.HandleErrorResponse(c, utils.UnverifiedEmailErrorResponse())
+			return nil
+		}
+
+		return c.Next()
+	}
+}
+
+// RoleMiddleware will reject requests if the access token doesn't have the role.
+// It must be used after [JWTAuthenticator.Middleware].
+func (a *JWTAuthenticator) RoleMiddleware(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals(JWTClaimsKey).(*Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InvalidTokenErrorResponse())
+			return nil
+		}
+
+		if claims.Role != role {
+			utils.HandleErrorResponse(c, utils.ForbiddenErrorResponse())
+			return nil
+		}
+
+		return c.Next()
+	}
+}
+
+// FirstPartyMiddleware will reject access tokens issued to oauth clients.
+// It must be used after [JWTAuthenticator.Middleware].
+func (a *JWTAuthenticator) FirstPartyMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals(JWTClaimsKey).(*Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InvalidTokenErrorResponse())
+			return nil
+		}
+
+		if claims.ClientId != "" {
+			utils.HandleErrorResponse(c, utils.ForbiddenErrorResponse())
+			return nil
+		}
+
+		return c.Next()
+	}
+}
+
+// ScopeMiddleware will reject requests if the access token doesn't have the read scope for
+// GET and HEAD requests or the write scope for other requests.
+// It must be used after [JWTAuthenticator.Middleware].
+func (a *JWTAuthenticator) ScopeMiddleware(readScope, writeScope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals(JWTClaimsKey).(*Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InvalidTokenErrorResponse())
+			return nil
+		}
+
+		scope := writeScope
+		if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+			scope = readScope
+		}
+
+		if !claims.HasScope(scope) {
+			utils.HandleErrorResponse(c, utils.InsufficientScopeErrorResponse())
+			return nil
+		}
+
+		return c.Next()
+	}
+}
+
 func NewJWTAuthenticator(conf *config.AuthConfig) *JWTAuthenticator {
 	return &JWTAuthenticator{conf.JwtSecret, conf.JwtIssuer}
 }
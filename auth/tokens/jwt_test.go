@@ -1,12 +1,18 @@
 package tokens
 
 import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"server/config"
+	"server/utils"
 	"testing"
 	"time"
 )
 
-var authenticator = NewJWTAuthenticator([]byte("secret"), "issuer")
+var authenticator = NewJWTAuthenticator(&config.AuthConfig{JwtSecret: []byte("secret"), JwtIssuer: "issuer"})
 
 func TestJWTAuthenticatorCreateRefreshToken(t *testing.T) {
 	token, err := authenticator.CreateRefreshToken(uuid.New(), time.Now().Add(time.Hour*24*14))
@@ -18,7 +24,7 @@ func TestJWTAuthenticatorCreateRefreshToken(t *testing.T) {
 }
 
 func TestJWTAuthenticatorCreateAccessToken(t *testing.T) {
-	token, err := authenticator.CreateAccessToken(1, time.Now().Add(time.Minute*10))
+	token, err := authenticator.CreateAccessToken(AccessClaims{UserId: 1, SessionId: uuid.New()}, time.Now().Add(time.Minute*10))
 	if err != nil {
 		t.Fatalf("Error creating access token token: %v", err)
 	}
@@ -49,7 +55,7 @@ func TestJWTAuthenticatorVerifyRefreshToken(t *testing.T) {
 
 func TestJWTAuthenticatorVerifyAccessToken(t *testing.T) {
 	// Create a new token.
-	token, err := authenticator.CreateAccessToken(1, time.Now().Add(time.Minute*10))
+	token, err := authenticator.CreateAccessToken(AccessClaims{UserId: 1, SessionId: uuid.New()}, time.Now().Add(time.Minute*10))
 	if err != nil {
 		t.Fatalf("Error creating access token: %v", err)
 	}
@@ -68,3 +74,118 @@ func TestJWTAuthenticatorVerifyAccessToken(t *testing.T) {
 		t.Fatal("Expected error, because the type for verification is wrong")
 	}
 }
+
+func TestJWTAuthenticatorVerifyReadOnlyAccessToken(t *testing.T) {
+	token, err := authenticator.CreateAccessToken(AccessClaims{UserId: 1, SessionId: uuid.New(), ReadOnly: true}, time.Now().Add(time.Minute*10))
+	if err != nil {
+		t.Fatalf("Error creating access token: %v", err)
+	}
+
+	claims, err := authenticator.VerifyToken(token, AccessTokenType)
+	if err != nil {
+		t.Fatalf("Error verifying access token: %v", err)
+	}
+
+	if !claims.ReadOnly {
+		t.Fatal("Expected the access token to be read only")
+	}
+}
+
+func TestJWTAuthenticatorVerifyAccessTokenRole(t *testing.T) {
+	token, err := authenticator.CreateAccessToken(AccessClaims{UserId: 1, SessionId: uuid.New(), Role: "admin"}, time.Now().Add(time.Minute*10))
+	if err != nil {
+		t.Fatalf("Error creating access token: %v", err)
+	}
+
+	claims, err := authenticator.VerifyToken(token, AccessTokenType)
+	if err != nil {
+		t.Fatalf("Error verifying access token: %v", err)
+	}
+
+	if claims.Role != "admin" {
+		t.Fatalf("Expected role admin, got %q", claims.Role)
+	}
+}
+
+func TestJWTAuthenticatorVerifyClientAccessTokenScopes(t *testing.T) {
+	token, err := authenticator.CreateAccessToken(
+		AccessClaims{UserId: 1, SessionId: uuid.New(), ClientId: "client", Scopes: []string{"tasks:read"}},
+		time.Now().Add(time.Minute*10),
+	)
+	if err != nil {
+		t.Fatalf("Error creating access token: %v", err)
+	}
+
+	claims, err := authenticator.VerifyToken(token, AccessTokenType)
+	if err != nil {
+		t.Fatalf("Error verifying access token: %v", err)
+	}
+
+	if claims.ClientId != "client" {
+		t.Fatalf("Expected client id client, got %q", claims.ClientId)
+	}
+
+	if !claims.HasScope("tasks:read") {
+		t.Fatal("Expected the token to have the tasks:read scope")
+	}
+
+	if claims.HasScope("tasks:write") {
+		t.Fatal("Expected the token to not have the tasks:write scope")
+	}
+
+	// First party tokens are not limited by scopes.
+	firstParty := Token{}
+	if !firstParty.HasScope("tasks:write") {
+		t.Fatal("Expected first party token to have every scope")
+	}
+}
+
+func TestMiddlewareErrorResponses(t *testing.T) {
+	app := fiber.New()
+	app.Get("/tasks", authenticator.Middleware(AccessTokenType), authenticator.ScopeMiddleware("tasks:read", "tasks:write"),
+		func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusNoContent)
+		})
+
+	token, err := authenticator.CreateAccessToken(
+		AccessClaims{UserId: 1, SessionId: uuid.New(), ClientId: "client", Scopes: []string{"tasks:write"}},
+		time.Now().Add(time.Minute*10),
+	)
+	if err != nil {
+		t.Fatalf("Error creating access token: %v", err)
+	}
+
+	tests := []struct {
+		authorization string
+		status        int
+		code          string
+	}{
+		{"", http.StatusUnauthorized, utils.CodeInvalidToken},
+		{"Bearer invalid", http.StatusUnauthorized, utils.CodeInvalidToken},
+		{"Bearer " + token, http.StatusForbidden, utils.CodeInsufficientScope},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		request.Header.Set(fiber.HeaderAccept, utils.ProblemContentType)
+		if test.authorization != "" {
+			request.Header.Set(fiber.HeaderAuthorization, test.authorization)
+		}
+
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var problem utils.Problem
+		if err = json.NewDecoder(response.Body).Decode(&problem); err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != test.status || problem.Code != test.code {
+			t.Fatalf("Expected %d %s, got %d %s", test.status, test.code, response.StatusCode, problem.Code)
+		}
+		if contentType := response.Header.Get(fiber.HeaderContentType); contentType != utils.ProblemContentType {
+			t.Fatalf("Expected problem content type, got %q", contentType)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// opaqueTokenLength is the number of random bytes in an opaque token.
+const opaqueTokenLength = 32
+
+// NewOpaqueToken will generate a random url safe token and its hash.
+// Only the hash should be stored, the token itself is given to the user.
+func NewOpaqueToken() (token string, hash string, err error) {
+	bytes := make([]byte, opaqueTokenLength)
+	if _, err = rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(bytes)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken will return the hex encoded SHA-256 hash of the token.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package tokens
+
+import "testing"
+
+func TestNewOpaqueToken(t *testing.T) {
+	token, hash, err := NewOpaqueToken()
+	if err != nil {
+		t.Fatalf("Error creating opaque token: %v", err)
+	}
+
+	if hash != HashOpaqueToken(token) {
+		t.Fatal("Hash of the token doesn't match the returned hash")
+	}
+
+	otherToken, _, err := NewOpaqueToken()
+	if err != nil {
+		t.Fatalf("Error creating opaque token: %v", err)
+	}
+
+	if token == otherToken {
+		t.Fatal("Expected different tokens")
+	}
+}
@@ -0,0 +1,38 @@
+package tokens
+
+import "testing"
+
+// The verifier and challenge are the example from RFC 7636 appendix B.
+const (
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	if !ValidCodeChallenge(testCodeChallenge) {
+		t.Fatal("Expected the challenge to be valid")
+	}
+
+	if !VerifyCodeChallenge(testCodeVerifier, testCodeChallenge) {
+		t.Fatal("Expected the verifier to match the challenge")
+	}
+
+	otherVerifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXK"
+	if VerifyCodeChallenge(otherVerifier, testCodeChallenge) {
+		t.Fatal("Expected other verifier to not match the challenge")
+	}
+}
+
+func TestValidCodeVerifier(t *testing.T) {
+	tests := map[string]bool{
+		testCodeVerifier: true,
+		"short":          false,
+		"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjX+": false,
+	}
+
+	for verifier, expected := range tests {
+		if ValidCodeVerifier(verifier) != expected {
+			t.Fatalf("Expected %q valid to be %v", verifier, expected)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+// Package client is the Go client of the task server API.
+//
+// The client keeps the tokens of the logged-in user and refreshes the access token when it
+// expires, so callers only log in once:
+//
+//	c := client.NewClient(&client.Config{BaseURL: "https://tasks.example.com"})
+//	if _, err := c.Login(ctx, models.LoginPayload{Identifier: "user", Password: password}); err != nil {
+//		return err
+//	}
+//	tasks, err := c.GetTasks(ctx)
+//
+// Errors of the server are returned as [*Error] and can be matched by their code with errors.Is,
+// for example errors.Is(err, client.ErrInvalidCredentials).
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"io"
+	"net/http"
+	"net/url"
+	"server/models"
+	"server/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is used when [Config.MaxRetries] is zero.
+	DefaultMaxRetries = 2
+	// DefaultRetryDelay is used when [Config.RetryDelay] is zero.
+	DefaultRetryDelay = 200 * time.Millisecond
+	// refreshLeeway is how long before its expiration the access token is refreshed,
+	// so it doesn't expire while the request is on its way.
+	refreshLeeway = 30 * time.Second
+)
+
+// ErrNotLoggedIn is returned by methods that require a user when the client has no tokens.
+var ErrNotLoggedIn = errors.New("client: not logged in")
+
+// Config struct holds the settings of [Client].
+type Config struct {
+	// BaseURL is the address of the server without the api prefix, for example https://tasks.example.com.
+	BaseURL string
+	// HTTPClient sends the requests. [http.DefaultClient] is used if it is nil.
+	HTTPClient *http.Client
+	// MaxRetries is how many times an idempotent request is retried after a network error or when the server
+	// is unavailable. Zero uses [DefaultMaxRetries], a negative number disables the retries.
+	MaxRetries int
+	// RetryDelay is the delay before the first retry. It doubles with every next retry.
+	RetryDelay time.Duration
+	// Tokens are the tokens of a previous session. They can be set also by [Client.SetTokens].
+	Tokens *models.TokenGroup
+	// OnTokens is called with the new tokens after every login and refresh, so they can be stored.
+	OnTokens func(models.TokenGroup)
+}
+
+// Client calls the api of the task server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	retryDelay time.Duration
+	onTokens   func(models.TokenGroup)
+
+	// mu guards the tokens. It is held during the refresh, so concurrent requests don't use
+	// the same refresh token twice, which would fail as refresh tokens are single-use.
+	mu     sync.Mutex
+	tokens *models.TokenGroup
+}
+
+// request struct holds a single api call.
+type request struct {
+	method string
+	path   string
+	body   any
+	// authenticated requests send the access token and refresh it when it expires.
+	authenticated bool
+	// noRetry disables the retries of requests that change state even if their method is idempotent.
+	noRetry bool
+}
+
+// Tokens will return the current tokens or nil if the client isn't logged in.
+func (c *Client) Tokens() *models.TokenGroup {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tokens == nil {
+		return nil
+	}
+	tokens := *c.tokens
+	return &tokens
+}
+
+// SetTokens will replace the tokens of the client, for example with tokens stored by a previous session.
+func (c *Client) SetTokens(tokens models.TokenGroup) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tokens = &tokens
+}
+
+// setTokens will store the tokens received from the server and pass them to [Config.OnTokens].
+// It must be called with the mutex held.
+func (c *Client) setTokens(tokens models.TokenGroup) {
+	c.tokens = &tokens
+	if c.onTokens != nil {
+		c.onTokens(tokens)
+	}
+}
+
+// do will send the request and decode the response into the result if it is not nil.
+// An expired access token is refreshed before the request, and once more if the server rejects it.
+func (c *Client) do(ctx context.Context, req request, result any) error {
+	if !req.authenticated {
+		return c.send(ctx, req, "", result)
+	}
+
+	accessToken, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.send(ctx, req, accessToken, result)
+	if !errors.Is(err, ErrInvalidToken) {
+		return err
+	}
+
+	// The token may have been revoked or signed by a rotated secret even though it isn't expired.
+	if accessToken, err = c.refresh(ctx, accessToken); err != nil {
+		return err
+	}
+	return c.send(ctx, req, accessToken, result)
+}
+
+// accessToken will return the access token, refreshed first if it expires soon.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	tokens := c.tokens
+	c.mu.Unlock()
+
+	if tokens == nil {
+		return "", ErrNotLoggedIn
+	}
+	if !expiresSoon(tokens.AccessToken) {
+		return tokens.AccessToken, nil
+	}
+	return c.refresh(ctx, tokens.AccessToken)
+}
+
+// refresh will exchange the refresh token for new tokens and return the new access token.
+// If another request already replaced the stale access token, the new one is returned without a refresh.
+func (c *Client) refresh(ctx context.Context, stale string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tokens == nil {
+		return "", ErrNotLoggedIn
+	}
+	if c.tokens.AccessToken != stale {
+		return c.tokens.AccessToken, nil
+	}
+
+	// The refresh token is consumed by the server, so a retry after a lost response would fail anyway.
+	var tokens models.TokenGroup
+	err := c.send(ctx, request{method: http.MethodGet, path: "/users/refresh", noRetry: true}, c.tokens.RefreshToken, &tokens)
+	if err != nil {
+		return "", err
+	}
+
+	c.setTokens(tokens)
+	return tokens.AccessToken, nil
+}
+
+// expiresSoon will return true if the access token expires within the leeway. The signature isn't
+// verified, the server does that. Tokens that can't be parsed are sent and left to the server to reject.
+func expiresSoon(accessToken string) bool {
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, &claims); err != nil || claims.ExpiresAt == nil {
+		return false
+	}
+	return time.Until(claims.ExpiresAt.Time) < refreshLeeway
+}
+
+// send will send the request with the token, retrying idempotent requests while the server is unavailable.
+func (c *Client) send(ctx context.Context, req request, token string, result any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return err
+		}
+	}
+
+	retries := 0
+	if idempotent(req) {
+		retries = c.maxRetries
+	}
+
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := c.sendOnce(ctx, req, body, token, result)
+		if attempt >= retries || !temporary(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// sendOnce will send the request a single time and decode the response.
+func (c *Client) sendOnce(ctx context.Context, req request, body []byte, token string, result any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+"/api/v1"+req.path, reader)
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Accept", utils.ProblemContentType+", application/json")
+	if body != nil {
+		httpRequest.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return decodeError(response)
+	}
+	if result == nil {
+		_, err = io.Copy(io.Discard, response.Body)
+		return err
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// idempotent will return true if the request can be sent again without changing the result.
+func idempotent(req request) bool {
+	if req.noRetry {
+		return false
+	}
+	switch req.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// temporary will return true if the request failed before it reached the server or the server is
+// unavailable, so a retry may succeed. Canceled requests are never retried.
+func temporary(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiError *Error
+	if errors.As(err, &apiError) {
+		switch apiError.Status {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+
+	// Errors of the transport are wrapped in url.Error, unlike errors decoding the response.
+	var urlError *url.Error
+	return errors.As(err, &urlError)
+}
+
+func NewClient(conf *Config) *Client {
+	httpClient := conf.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	maxRetries := conf.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultMaxRetries
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	retryDelay := conf.RetryDelay
+	if retryDelay == 0 {
+		retryDelay = DefaultRetryDelay
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(conf.BaseURL, "/"),
+		httpClient: httpClient,
+		maxRetries: maxRetries,
+		retryDelay: retryDelay,
+		onTokens:   conf.OnTokens,
+		tokens:     conf.Tokens,
+	}
+}
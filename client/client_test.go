@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server/models"
+	"server/utils"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient will create a client of the server that is logged in with the access token.
+func newTestClient(t *testing.T, handler http.HandlerFunc, accessToken string) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewClient(&Config{
+		BaseURL:    server.URL,
+		RetryDelay: time.Millisecond,
+		Tokens:     &models.TokenGroup{AccessToken: accessToken, RefreshToken: "refresh"},
+	})
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}, "access")
+
+	if _, err := c.GetTasks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", calls.Load())
+	}
+}
+
+func TestDoesNotRetryPost(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, "access")
+
+	_, err := c.AddTask(context.Background(), models.NewTaskPayload{Name: "task"})
+	var apiError *Error
+	if !errors.As(err, &apiError) || apiError.Status != http.StatusServiceUnavailable {
+		t.Fatalf("Expected unavailable error, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("Expected 1 attempt, got %d", calls.Load())
+	}
+}
+
+func TestDecodesErrorFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		target      error
+		problems    int
+		fields      int
+	}{
+		{
+			name:        "problem",
+			contentType: utils.ProblemContentType,
+			body: `{"status":400,"detail":"Password is too short","code":"weak_password","errors":[
+				{"field":"password","code":"weak_password","message":"Password is too short"},
+				{"field":"password","code":"weak_password","message":"Password needs a digit"}]}`,
+			target:   ErrWeakPassword,
+			problems: 2,
+			fields:   2,
+		},
+		{
+			name:        "legacy",
+			contentType: "application/json",
+			body:        `{"code":"weak_password","message":"Password does not meet the requirements","status":400,"errors":["a","b"]}`,
+			target:      ErrWeakPassword,
+			problems:    2,
+		},
+		{
+			name:        "plain text",
+			contentType: "text/plain",
+			body:        "Bad Request",
+			target:      &Error{Code: "bad_request"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(test.body))
+			}, "")
+
+			err := c.Register(context.Background(), models.RegistrationsPayload{})
+			if !errors.Is(err, test.target) {
+				t.Fatalf("Expected %v, got %v", test.target, err)
+			}
+
+			var apiError *Error
+			errors.As(err, &apiError)
+			if apiError.Message == "" {
+				t.Error("Expected a message")
+			}
+			if len(apiError.Problems) != test.problems || len(apiError.Fields) != test.fields {
+				t.Errorf("Expected %d problems and %d fields, got %v", test.problems, test.fields, apiError)
+			}
+		})
+	}
+}
+
+func TestConcurrentRequestsRefreshOnce(t *testing.T) {
+	var refreshes atomic.Int32
+	var saved []models.TokenGroup
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/users/refresh":
+			refreshes.Add(1)
+			w.Write([]byte(`{"access_token":"new","refresh_token":"refresh2"}`))
+		case r.Header.Get("Authorization") == "Bearer new":
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"invalid_token","message":"Invalid Token","status":401}`))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(&Config{
+		BaseURL: server.URL,
+		Tokens:  &models.TokenGroup{AccessToken: "revoked", RefreshToken: "refresh"},
+		OnTokens: func(tokens models.TokenGroup) {
+			mu.Lock()
+			saved = append(saved, tokens)
+			mu.Unlock()
+		},
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetTasks(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if refreshes.Load() != 1 {
+		t.Fatalf("Expected 1 refresh, got %d", refreshes.Load())
+	}
+	if len(saved) != 1 || saved[0].RefreshToken != "refresh2" {
+		t.Fatalf("Expected the new tokens to be saved once, got %v", saved)
+	}
+}
+
+func TestRequiresLogin(t *testing.T) {
+	c := NewClient(&Config{BaseURL: "http://localhost"})
+	if _, err := c.GetTasks(context.Background()); !errors.Is(err, ErrNotLoggedIn) {
+		t.Fatalf("Expected %v, got %v", ErrNotLoggedIn, err)
+	}
+}
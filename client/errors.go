@@ -0,0 +1,131 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"server/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors of the server by their code. They are matched with errors.Is against the returned [*Error].
+var (
+	ErrValidationFailed   = &Error{Code: utils.CodeValidationFailed}
+	ErrInvalidToken       = &Error{Code: utils.CodeInvalidToken}
+	ErrInvalidCredentials = &Error{Code: utils.CodeInvalidCredentials}
+	ErrEmailUnverified    = &Error{Code: utils.CodeEmailUnverified}
+	ErrAccountDisabled    = &Error{Code: utils.CodeAccountDisabled}
+	ErrForbidden          = &Error{Code: utils.CodeForbidden}
+	ErrInsufficientScope  = &Error{Code: utils.CodeInsufficientScope}
+	ErrTooManyRequests    = &Error{Code: utils.CodeTooManyRequests}
+	ErrEmailTaken         = &Error{Code: utils.CodeEmailTaken}
+	ErrUsernameTaken      = &Error{Code: utils.CodeUsernameTaken}
+	ErrWeakPassword       = &Error{Code: utils.CodeWeakPassword}
+	ErrTaskNotFound       = &Error{Code: utils.CodeTaskNotFound}
+	ErrInvalidPriority    = &Error{Code: utils.CodeInvalidPriority}
+	ErrInvalidId          = &Error{Code: utils.CodeInvalidId}
+	ErrInternal           = &Error{Code: utils.CodeInternalError}
+)
+
+// Error is returned when the server responds with an error. Both the problem and the legacy
+// format of the server are understood.
+type Error struct {
+	// Status is the HTTP status code of the response.
+	Status int
+	// Code is the stable code of the error, for example email_taken.
+	Code    string
+	Message string
+	// Problems are all problems when there is more than one, for example every unmet password requirement.
+	Problems []string
+	// Fields are the problems of the payload fields.
+	Fields []utils.FieldError
+	// RequestId is the id of the request to quote when reporting the error.
+	RequestId string
+	// RetryAfter is how long to wait before the next attempt when the requests are rate limited.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.Status)
+	}
+	if len(e.Problems) > 0 {
+		message += ": " + strings.Join(e.Problems, "; ")
+	}
+	if e.Code != "" {
+		message += " (" + e.Code + ")"
+	}
+	return message
+}
+
+// Is will match errors with the same code, so errors.Is(err, [ErrTaskNotFound]) works.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// errorBody struct holds the fields of both error formats of the server.
+type errorBody struct {
+	Code string `json:"code"`
+	// Message is the message of the legacy format.
+	Message string `json:"message"`
+	// Detail is the message of the problem format.
+	Detail    string `json:"detail"`
+	RequestId string `json:"request_id"`
+	// Errors are strings in the legacy format and field errors in the problem format.
+	Errors json.RawMessage `json:"errors"`
+}
+
+// decodeError will create [Error] from the error response. Responses without a JSON body,
+// for example from a proxy, get the code of their status.
+func decodeError(response *http.Response) *Error {
+	result := &Error{
+		Status: response.StatusCode,
+		Code:   utils.StatusCode(response.StatusCode),
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		result.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	content, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return result
+	}
+
+	var body errorBody
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if !strings.HasSuffix(mediaType, "json") || json.Unmarshal(content, &body) != nil {
+		result.Message = strings.TrimSpace(string(content))
+		return result
+	}
+
+	if body.Code != "" {
+		result.Code = body.Code
+	}
+	result.Message = body.Message
+	if mediaType == utils.ProblemContentType {
+		result.Message = body.Detail
+	}
+	result.RequestId = body.RequestId
+
+	if len(body.Errors) > 0 {
+		if mediaType == utils.ProblemContentType {
+			_ = json.Unmarshal(body.Errors, &result.Fields)
+			for _, field := range result.Fields {
+				result.Problems = append(result.Problems, field.Message)
+			}
+			// A single problem is already the message.
+			if len(result.Problems) == 1 {
+				result.Problems = nil
+			}
+		} else {
+			_ = json.Unmarshal(body.Errors, &result.Problems)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"net/http"
+	"server/models"
+)
+
+// GetTasks will return all tasks of the user.
+func (c *Client) GetTasks(ctx context.Context) ([]models.TaskPayload, error) {
+	var tasks []models.TaskPayload
+	err := c.do(ctx, request{method: http.MethodGet, path: "/tasks/get", authenticated: true}, &tasks)
+	return tasks, err
+}
+
+// AddTask will add a new task and return it with the id assigned by the server.
+func (c *Client) AddTask(ctx context.Context, payload models.NewTaskPayload) (*models.TaskPayload, error) {
+	var task models.TaskPayload
+	err := c.do(ctx, request{method: http.MethodPost, path: "/tasks/add", body: &payload, authenticated: true}, &task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// UpdateTask will replace the task with the same id.
+func (c *Client) UpdateTask(ctx context.Context, task models.TaskPayload) error {
+	return c.do(ctx, request{method: http.MethodPut, path: "/tasks/update", body: &task, authenticated: true}, nil)
+}
+
+// DeleteTask will delete the task. A retried delete of an already deleted task returns [ErrTaskNotFound].
+func (c *Client) DeleteTask(ctx context.Context, taskId uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/tasks/delete/" + taskId.String(), authenticated: true}, nil)
+}
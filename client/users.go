@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"server/models"
+)
+
+// Register will create a new user. The user must verify the email before logging in, unless the
+// server allows unverified users.
+func (c *Client) Register(ctx context.Context, payload models.RegistrationsPayload) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/users/register", body: &payload}, nil)
+}
+
+// Login will log in the user and keep the tokens for the next requests.
+func (c *Client) Login(ctx context.Context, payload models.LoginPayload) (*models.TokenGroup, error) {
+	var tokens models.TokenGroup
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/users/login", body: &payload}, &tokens); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.setTokens(tokens)
+	c.mu.Unlock()
+	return &tokens, nil
+}
+
+// Refresh will exchange the refresh token for new tokens. It is called automatically when
+// the access token expires, so it is needed only to extend the session explicitly.
+func (c *Client) Refresh(ctx context.Context) (*models.TokenGroup, error) {
+	c.mu.Lock()
+	tokens := c.tokens
+	c.mu.Unlock()
+	if tokens == nil {
+		return nil, ErrNotLoggedIn
+	}
+
+	if _, err := c.refresh(ctx, tokens.AccessToken); err != nil {
+		return nil, err
+	}
+	return c.Tokens(), nil
+}
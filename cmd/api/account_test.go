@@ -0,0 +1,128 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"server/models"
+	"server/utils"
+	"testing"
+	"time"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+	token := userToken(1)
+
+	_, err = userService.DeleteAccount(ctx, token, models.DeleteAccountPayload{Password: "wrong-password1"})
+	if err == nil || err.Code != utils.CodeInvalidCredentials {
+		t.Fatalf("Expected invalid credentials, got %v", err)
+	}
+	if user := store.users.users[0]; user.DeleteAfter != nil {
+		t.Fatalf("Expected no scheduled deletion, got %v", user.DeleteAfter)
+	}
+
+	deletion, err := userService.DeleteAccount(ctx, token, models.DeleteAccountPayload{Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delay := time.Until(deletion.DeleteAfter.Time); delay <= 0 || delay > time.Hour {
+		t.Fatalf("Expected the deletion after the grace period, got %v", deletion.DeleteAfter)
+	}
+	if user := store.users.users[0]; user.DeleteAfter == nil || !user.DeleteAfter.Equal(deletion.DeleteAfter.Time) {
+		t.Fatalf("Expected the deletion to be scheduled, got %v", user.DeleteAfter)
+	}
+	if len(store.tokens.tokens) != 0 {
+		t.Fatalf("Expected the sessions to be revoked, got %d", len(store.tokens.tokens))
+	}
+
+	// Logging in during the grace period cancels the deletion.
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+	if user := store.users.users[0]; user.DeleteAfter != nil {
+		t.Fatalf("Expected the deletion to be cancelled, got %v", user.DeleteAfter)
+	}
+}
+
+func TestExportData(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	for _, payload := range []models.RegistrationsPayload{
+		{Email: "user@example.com", Username: "user", Password: "password1"},
+		{Email: "other@example.com", Username: "other", Password: "password1"},
+	} {
+		if err := userService.Register(ctx, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+	for userId, name := range map[int]string{1: "Own task", 2: "Task of other user"} {
+		task := &models.TaskPayload{Id: uuid.New(), NewTaskPayload: models.NewTaskPayload{Name: name, Priority: "Low"}}
+		if err := store.tasks.AddTask(ctx, task, userId); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive, errorResponse := userService.ExportData(ctx, userToken(1))
+	if errorResponse != nil {
+		t.Fatal(errorResponse)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+	readJSON := func(name string, data any) {
+		file, ok := files[name]
+		if !ok {
+			t.Fatalf("Expected %s in the archive, got %v", name, files)
+		}
+		content, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer content.Close()
+		if err = json.NewDecoder(content).Decode(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var profile models.Profile
+	readJSON("profile.json", &profile)
+	if profile.Id != 1 || profile.Email != "user@example.com" || profile.Username != "user" {
+		t.Fatalf("Expected the profile of the user, got %+v", profile)
+	}
+
+	var tasks []models.TaskPayload
+	readJSON("tasks.json", &tasks)
+	if len(tasks) != 1 || tasks[0].Name != "Own task" {
+		t.Fatalf("Expected only the tasks of the user, got %v", tasks)
+	}
+
+	var sessions []models.Session
+	readJSON("sessions.json", &sessions)
+	if len(sessions) != 1 {
+		t.Fatalf("Expected the session of the user, got %v", sessions)
+	}
+}
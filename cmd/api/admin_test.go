@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"net/http"
+	"server/audit"
+	"server/auth/tokens"
+	"server/client"
+	"server/models"
+	"server/services"
+	"server/utils"
+	"testing"
+	"time"
+)
+
+func TestDisabledAccount(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	adminService := services.NewDefaultAdminService(store.users, store.tokens, store.tasks, store.audit, audit.NewRepositoryRecorder(store.audit))
+	ctx := context.Background()
+
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+	var refreshToken tokens.Token
+	for tokenId := range store.tokens.tokens {
+		refreshToken = tokens.Token{TokenType: tokens.RefreshTokenType, RegisteredClaims: jwt.RegisteredClaims{ID: tokenId.String()}}
+	}
+
+	// The account disabled while the user has a session can't be refreshed.
+	if _, err := store.users.SetDisabled(ctx, 1, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = userService.Refresh(ctx, refreshToken); err == nil || err.Code != utils.CodeAccountDisabled {
+		t.Fatalf("Expected the refresh to be refused, got %v", err)
+	}
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err == nil || err.Code != utils.CodeAccountDisabled {
+		t.Fatalf("Expected the login to be refused, got %v", err)
+	}
+
+	if err = adminService.EnableUser(ctx, userToken(2), 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Disabling the account by an admin revokes the sessions too.
+	if err = adminService.DisableUser(ctx, userToken(1), 1); err == nil || err.Code != utils.CodeCannotDisableSelf {
+		t.Fatalf("Expected the admin to not disable themselves, got %v", err)
+	}
+	if err = adminService.DisableUser(ctx, userToken(2), 3); err == nil || err.Code != utils.CodeUserNotFound {
+		t.Fatalf("Expected the user to not be found, got %v", err)
+	}
+	if err = adminService.DisableUser(ctx, userToken(2), 1); err != nil {
+		t.Fatal(err)
+	}
+	if user := store.users.users[0]; !user.Disabled || len(store.tokens.tokens) != 0 {
+		t.Fatalf("Expected the disabled user without sessions, got %+v and %d sessions", user, len(store.tokens.tokens))
+	}
+}
+
+func TestAdminEndToEnd(t *testing.T) {
+	url, authenticator := startServer(t)
+	ctx := context.Background()
+
+	c := client.NewClient(&client.Config{BaseURL: url})
+	err := c.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+	date := models.ISOTime{Time: time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)}
+	if _, err = c.AddTask(ctx, models.NewTaskPayload{Name: "Task", Description: "Task", Priority: "High", Date: date}); err != nil {
+		t.Fatal(err)
+	}
+
+	adminToken, err := authenticator.CreateAccessToken(tokens.AccessClaims{UserId: 100, SessionId: uuid.New(), Role: string(models.RoleAdmin)}, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := func(method, path string, result any) int {
+		return doRequest(t, method, url+"/api/v1/admin"+path, adminToken, fiber.MIMEApplicationJSON, "", result)
+	}
+
+	t.Run("users are forbidden", func(t *testing.T) {
+		status, code := send(t, http.MethodGet, url+"/api/v1/admin/users", c.Tokens().AccessToken, "")
+		if status != http.StatusForbidden || code != utils.CodeForbidden {
+			t.Fatalf("Expected forbidden, got %d %q", status, code)
+		}
+	})
+
+	t.Run("search users", func(t *testing.T) {
+		var users []models.AdminUser
+		if status := admin(http.MethodGet, "/users?query=USER", &users); status != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+		}
+		if len(users) != 1 || users[0].Username != "user" {
+			t.Fatalf("Expected the user, got %v", users)
+		}
+	})
+
+	t.Run("count tasks", func(t *testing.T) {
+		var counts models.TaskCounts
+		if status := admin(http.MethodGet, "/users/1/tasks/count", &counts); status != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+		}
+		if counts.Total != 1 || counts.ByPriority["High"] != 1 {
+			t.Fatalf("Expected one high priority task, got %+v", counts)
+		}
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		var errorResponse utils.ErrorResponse
+		if status := admin(http.MethodPut, "/users/invalid/disable", &errorResponse); status != http.StatusBadRequest || errorResponse.Code != utils.CodeInvalidId {
+			t.Fatalf("Expected invalid id, got %d %q", status, errorResponse.Code)
+		}
+		if status := admin(http.MethodPut, "/users/2/disable", &errorResponse); status != http.StatusNotFound || errorResponse.Code != utils.CodeUserNotFound {
+			t.Fatalf("Expected user not found, got %d %q", status, errorResponse.Code)
+		}
+
+		if status := admin(http.MethodPut, "/users/1/disable", nil); status != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+		}
+		if _, err := c.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); !errors.Is(err, client.ErrAccountDisabled) {
+			t.Fatalf("Expected %v, got %v", client.ErrAccountDisabled, err)
+		}
+
+		var events []models.AuditEvent
+		if status := admin(http.MethodGet, "/audit?user_id=1&type=account_disabled", &events); status != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+		}
+		if len(events) != 1 || events[0].Outcome != models.AuditSuccess || events[0].ActorId == nil || *events[0].ActorId != 100 {
+			t.Fatalf("Expected the event caused by the admin, got %v", events)
+		}
+	})
+
+	t.Run("enable", func(t *testing.T) {
+		if status := admin(http.MethodPut, "/users/1/enable", nil); status != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+		}
+		if _, err := c.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		if status := admin(http.MethodPost, "/users/1/logout", nil); status != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+		}
+
+		c.SetTokens(models.TokenGroup{AccessToken: "invalid", RefreshToken: c.Tokens().RefreshToken})
+		if _, err := c.GetTasks(ctx); !errors.Is(err, client.ErrInvalidToken) {
+			t.Fatalf("Expected %v, got %v", client.ErrInvalidToken, err)
+		}
+	})
+}
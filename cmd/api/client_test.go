@@ -0,0 +1,861 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"server/audit"
+	"server/auth/passwords"
+	"server/auth/tokens"
+	"server/client"
+	"server/config"
+	"server/handlers"
+	"server/health"
+	"server/mail"
+	"server/models"
+	"server/ratelimit"
+	"server/repositories"
+	"server/services"
+	"server/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryUsers is an in-memory [repositories.UserRepository] with the methods used by the tested flows.
+type memoryUsers struct {
+	repositories.UserRepository
+	mu    sync.Mutex
+	users []models.User
+	// missChecks makes the uniqueness checks miss the values of other users, as if they were taken after the check.
+	missChecks bool
+}
+
+func (r *memoryUsers) find(match func(models.User) bool) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if match(user) {
+			return user, nil
+		}
+	}
+	return models.User{}, sql.ErrNoRows
+}
+
+func (r *memoryUsers) update(userId int, change func(*models.User)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		if r.users[i].Id == userId {
+			change(&r.users[i])
+		}
+	}
+}
+
+func (r *memoryUsers) CheckIfEmailExists(_ context.Context, email string) (bool, error) {
+	_, err := r.GetUserByEmail(context.Background(), email)
+	return err == nil && !r.missChecks, nil
+}
+
+func (r *memoryUsers) CheckIfUsernameExists(_ context.Context, username string) (bool, error) {
+	_, err := r.GetUserByUsername(context.Background(), username)
+	return err == nil && !r.missChecks, nil
+}
+
+func (r *memoryUsers) AddUser(_ context.Context, email string, username string, password string) (int, error) {
+	if _, err := r.GetUserByEmail(context.Background(), email); err == nil {
+		return 0, repositories.ErrDuplicateEmail
+	}
+	if _, err := r.GetUserByUsername(context.Background(), username); err == nil {
+		return 0, repositories.ErrDuplicateUsername
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := len(r.users) + 1
+	r.users = append(r.users, models.User{Id: id, Email: email, Username: username, Password: password, Role: models.RoleUser})
+	return id, nil
+}
+
+func (r *memoryUsers) GetUserByEmail(_ context.Context, email string) (models.User, error) {
+	return r.find(func(user models.User) bool { return strings.EqualFold(user.Email, email) })
+}
+
+func (r *memoryUsers) GetUserByUsername(_ context.Context, username string) (models.User, error) {
+	return r.find(func(user models.User) bool { return strings.EqualFold(user.Username, username) })
+}
+
+func (r *memoryUsers) GetUserById(_ context.Context, userId int) (models.User, error) {
+	return r.find(func(user models.User) bool { return user.Id == userId })
+}
+
+func (r *memoryUsers) UpdateEmail(_ context.Context, userId int, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Id != userId && strings.EqualFold(user.Email, email) {
+			return repositories.ErrDuplicate
+		}
+	}
+	for i, user := range r.users {
+		if user.Id == userId {
+			r.users[i].EmailVerified = user.EmailVerified && strings.EqualFold(user.Email, email)
+			r.users[i].Email = email
+		}
+	}
+	return nil
+}
+
+func (r *memoryUsers) UpdateUsername(_ context.Context, userId int, username string) error {
+	if _, err := r.GetUserByUsername(context.Background(), username); err == nil {
+		if user, _ := r.GetUserById(context.Background(), userId); !strings.EqualFold(user.Username, username) {
+			return repositories.ErrDuplicate
+		}
+	}
+
+	r.update(userId, func(user *models.User) { user.Username = username })
+	return nil
+}
+
+func (r *memoryUsers) MarkEmailVerified(_ context.Context, userId int) error {
+	r.update(userId, func(user *models.User) { user.EmailVerified = true })
+	return nil
+}
+
+func (r *memoryUsers) UpdatePassword(_ context.Context, userId int, password string) error {
+	r.update(userId, func(user *models.User) { user.Password = password })
+	return nil
+}
+
+func (r *memoryUsers) ScheduleDeletion(_ context.Context, userId int, deleteAfter time.Time) error {
+	r.update(userId, func(user *models.User) { user.DeleteAfter = &deleteAfter })
+	return nil
+}
+
+func (r *memoryUsers) CancelDeletion(_ context.Context, userId int) error {
+	r.update(userId, func(user *models.User) { user.DeleteAfter = nil })
+	return nil
+}
+
+func (r *memoryUsers) SearchUsers(_ context.Context, query string, limit int, offset int) ([]models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	query = strings.ToLower(query)
+	result := make([]models.User, 0, limit)
+	for _, user := range r.users {
+		if strings.Contains(strings.ToLower(user.Email), query) || strings.Contains(strings.ToLower(user.Username), query) {
+			result = append(result, user)
+		}
+	}
+	result = result[min(offset, len(result)):]
+	return result[:min(limit, len(result))], nil
+}
+
+func (r *memoryUsers) SetDisabled(_ context.Context, userId int, disabled bool) (bool, error) {
+	if _, err := r.GetUserById(context.Background(), userId); err != nil {
+		return false, nil
+	}
+
+	r.update(userId, func(user *models.User) { user.Disabled = disabled })
+	return true, nil
+}
+
+// memoryTokens is an in-memory [repositories.TokenRepository] of the refresh tokens.
+type memoryTokens struct {
+	repositories.TokenRepository
+	mu     sync.Mutex
+	tokens map[uuid.UUID]int
+	// clients holds the clients of the tokens issued to oauth clients.
+	clients map[uuid.UUID]string
+	scopes  map[uuid.UUID][]string
+}
+
+func (r *memoryTokens) AddToken(_ context.Context, tokenId uuid.UUID, _ time.Time, userId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[tokenId] = userId
+	return nil
+}
+
+func (r *memoryTokens) CheckToken(_ context.Context, tokenId uuid.UUID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userId, ok := r.tokens[tokenId]
+	if _, client := r.clients[tokenId]; !ok || client {
+		return 0, sql.ErrNoRows
+	}
+	return userId, nil
+}
+
+func (r *memoryTokens) AddClientToken(_ context.Context, tokenId uuid.UUID, _ time.Time, userId int, clientId string, scopes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[tokenId] = userId
+	r.clients[tokenId] = clientId
+	r.scopes[tokenId] = scopes
+	return nil
+}
+
+func (r *memoryTokens) CheckClientToken(_ context.Context, tokenId uuid.UUID, clientId string) (int, []string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userId, ok := r.tokens[tokenId]
+	if !ok || r.clients[tokenId] != clientId {
+		return 0, nil, sql.ErrNoRows
+	}
+	return userId, r.scopes[tokenId], nil
+}
+
+func (r *memoryTokens) GetUserTokens(_ context.Context, userId int) ([]models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]models.Session, 0)
+	for tokenId, tokenUserId := range r.tokens {
+		if tokenUserId == userId {
+			result = append(result, models.Session{Id: tokenId})
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryTokens) DeleteToken(_ context.Context, tokenId uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.tokens, tokenId)
+	delete(r.clients, tokenId)
+	delete(r.scopes, tokenId)
+	return nil
+}
+
+func (r *memoryTokens) DeleteUserTokens(_ context.Context, userId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tokenId, tokenUserId := range r.tokens {
+		if tokenUserId == userId {
+			delete(r.tokens, tokenId)
+		}
+	}
+	return nil
+}
+
+// memoryIdentities is an in-memory [repositories.IdentityRepository].
+type memoryIdentities struct {
+	mu         sync.Mutex
+	identities map[string]int
+}
+
+func (r *memoryIdentities) AddIdentity(_ context.Context, issuer string, subject string, userId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.identities[issuer+" "+subject] = userId
+	return nil
+}
+
+func (r *memoryIdentities) GetIdentityUser(_ context.Context, issuer string, subject string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userId, ok := r.identities[issuer+" "+subject]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return userId, nil
+}
+
+// memoryOAuth is an in-memory [repositories.OAuthRepository] of the clients and authorization codes.
+type memoryOAuth struct {
+	repositories.OAuthRepository
+	mu      sync.Mutex
+	clients map[string]models.OAuthClient
+	codes   map[string]models.AuthorizationCode
+}
+
+func (r *memoryOAuth) AddClient(_ context.Context, client models.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clients[client.Id] = client
+	return nil
+}
+
+func (r *memoryOAuth) GetClient(_ context.Context, clientId string) (models.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	client, ok := r.clients[clientId]
+	if !ok {
+		return models.OAuthClient{}, sql.ErrNoRows
+	}
+	return client, nil
+}
+
+func (r *memoryOAuth) AddAuthorizationCode(_ context.Context, codeHash string, code models.AuthorizationCode, _ time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.codes[codeHash] = code
+	return nil
+}
+
+func (r *memoryOAuth) ConsumeAuthorizationCode(_ context.Context, codeHash string) (models.AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, ok := r.codes[codeHash]
+	if !ok {
+		return models.AuthorizationCode{}, sql.ErrNoRows
+	}
+	delete(r.codes, codeHash)
+	return code, nil
+}
+
+// memoryResets is an in-memory [repositories.PasswordResetRepository].
+type memoryResets struct {
+	mu     sync.Mutex
+	tokens map[string]memoryToken
+}
+
+// memoryToken holds the user and the expiration of a single-use token.
+type memoryToken struct {
+	userId int
+	exp    time.Time
+}
+
+func (r *memoryResets) AddResetToken(_ context.Context, tokenHash string, exp time.Time, userId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[tokenHash] = memoryToken{userId, exp}
+	return nil
+}
+
+func (r *memoryResets) GetResetTokenUser(_ context.Context, tokenHash string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok || token.exp.Before(time.Now()) {
+		return 0, sql.ErrNoRows
+	}
+	return token.userId, nil
+}
+
+func (r *memoryResets) ConsumeResetToken(ctx context.Context, tokenHash string) (int, error) {
+	userId, err := r.GetResetTokenUser(ctx, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.tokens, tokenHash)
+	return userId, nil
+}
+
+func (r *memoryResets) DeleteUserResetTokens(_ context.Context, userId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tokenHash, token := range r.tokens {
+		if token.userId == userId {
+			delete(r.tokens, tokenHash)
+		}
+	}
+	return nil
+}
+
+// memoryMailer keeps the sent messages. If err is set sending fails.
+type memoryMailer struct {
+	mu       sync.Mutex
+	messages []mail.Message
+	err      error
+}
+
+func (m *memoryMailer) Send(_ context.Context, message mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// memoryVerifications is a [repositories.EmailVerificationRepository] that forgets the tokens.
+type memoryVerifications struct {
+	repositories.EmailVerificationRepository
+}
+
+func (r *memoryVerifications) DeleteUserVerificationTokens(context.Context, int) error {
+	return nil
+}
+
+func (r *memoryVerifications) AddVerificationToken(context.Context, string, time.Time, int) error {
+	return nil
+}
+
+// memoryTasks is an in-memory [repositories.TaskRepository] with the default priorities.
+type memoryTasks struct {
+	repositories.TaskRepository
+	mu    sync.Mutex
+	tasks map[uuid.UUID]models.TaskPayload
+	users map[uuid.UUID]int
+}
+
+func (r *memoryTasks) GetTasks(_ context.Context, userId int) ([]models.TaskPayload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]models.TaskPayload, 0)
+	for id, task := range r.tasks {
+		if r.users[id] == userId {
+			result = append(result, task)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryTasks) CheckPriority(_ context.Context, priority string) (bool, error) {
+	switch priority {
+	case "Low", "Medium", "High", "Vital":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (r *memoryTasks) AddTask(_ context.Context, task *models.TaskPayload, userId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tasks[task.Id] = *task
+	r.users[task.Id] = userId
+	return nil
+}
+
+func (r *memoryTasks) UpdateTask(_ context.Context, task *models.TaskPayload, userId int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tasks[task.Id]; !ok || r.users[task.Id] != userId {
+		return false, nil
+	}
+	r.tasks[task.Id] = *task
+	return true, nil
+}
+
+func (r *memoryTasks) DeleteTask(_ context.Context, taskId uuid.UUID, userId int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tasks[taskId]; !ok || r.users[taskId] != userId {
+		return false, nil
+	}
+	delete(r.tasks, taskId)
+	delete(r.users, taskId)
+	return true, nil
+}
+
+func (r *memoryTasks) CountTasksByPriority(_ context.Context, userId int) (map[string]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := map[string]int{}
+	for id, task := range r.tasks {
+		if r.users[id] == userId {
+			result[task.Priority]++
+		}
+	}
+	return result, nil
+}
+
+// memoryAudit is an in-memory [repositories.AuditRepository] filtering the events by the user and the type.
+type memoryAudit struct {
+	mu     sync.Mutex
+	events []models.AuditEvent
+}
+
+func (r *memoryAudit) AddEvent(_ context.Context, event models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.Id = int64(len(r.events) + 1)
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memoryAudit) GetEvents(_ context.Context, query models.AuditQuery) ([]models.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]models.AuditEvent, 0)
+	for i := len(r.events) - 1; i >= 0; i-- {
+		event := r.events[i]
+		if query.UserId != 0 && (event.UserId == nil || *event.UserId != query.UserId) {
+			continue
+		}
+		if query.Type != "" && event.Type != query.Type {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result, nil
+}
+
+// discardRecorder drops the audit events.
+type discardRecorder struct{}
+
+func (discardRecorder) Record(context.Context, models.AuditEvent) {}
+
+// memoryStore holds the in-memory repositories shared by the services of a test.
+type memoryStore struct {
+	users      *memoryUsers
+	tokens     *memoryTokens
+	tasks      *memoryTasks
+	identities *memoryIdentities
+	oauth      *memoryOAuth
+	resets     *memoryResets
+	mailer     *memoryMailer
+	audit      *memoryAudit
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		users:      &memoryUsers{},
+		tokens:     &memoryTokens{tokens: map[uuid.UUID]int{}, clients: map[uuid.UUID]string{}, scopes: map[uuid.UUID][]string{}},
+		tasks:      &memoryTasks{tasks: map[uuid.UUID]models.TaskPayload{}, users: map[uuid.UUID]int{}},
+		identities: &memoryIdentities{identities: map[string]int{}},
+		oauth:      &memoryOAuth{clients: map[string]models.OAuthClient{}, codes: map[string]models.AuthorizationCode{}},
+		resets:     &memoryResets{tokens: map[string]memoryToken{}},
+		mailer:     &memoryMailer{},
+		audit:      &memoryAudit{},
+	}
+}
+
+// newUserService will create the user service of the in-memory repositories.
+func newUserService(store *memoryStore, authenticator *tokens.JWTAuthenticator, limiter ratelimit.Limiter) *services.DefaultUseService {
+	return services.NewDefaultUserService(
+		store.users,
+		store.tokens,
+		store.resets,
+		&memoryVerifications{},
+		store.identities,
+		store.tasks,
+		nil,
+		discardRecorder{},
+		store.mailer,
+		authenticator,
+		passwords.NewHasher(passwords.NewBcrypt(4)),
+		passwords.NewPolicy(&config.PasswordPolicyConfig{MinLength: 8, MaxLength: 64, RequireDigit: true}, nil),
+		limiter,
+		config.UnverifiedAllow,
+		time.Hour,
+	)
+}
+
+// newTestUserService will create the user service of the in-memory repositories with a limiter that doesn't block the tests.
+func newTestUserService(store *memoryStore) *services.DefaultUseService {
+	authenticator := tokens.NewJWTAuthenticator(&config.AuthConfig{JwtSecret: []byte("secret"), JwtIssuer: "test"})
+	return newUserService(store, authenticator, ratelimit.NewMemoryLimiter(ratelimit.Policy{FreeAttempts: 100, MaxAttempts: 100, Window: time.Hour}))
+}
+
+// userToken will return the access token of the user as parsed by the middleware.
+func userToken(userId int) tokens.Token {
+	return tokens.Token{TokenType: tokens.AccessTokenType, RegisteredClaims: jwt.RegisteredClaims{Subject: strconv.Itoa(userId)}}
+}
+
+// startServer will serve the app of the server with in-memory repositories on a random port.
+// It returns the url of the server and the authenticator that signs its tokens.
+func startServer(t *testing.T) (string, *tokens.JWTAuthenticator) {
+	conf := &config.Config{
+		AuthConfig: config.AuthConfig{
+			JwtSecret:        []byte("test-secret-that-is-long-enough!"),
+			JwtIssuer:        "test",
+			UnverifiedPolicy: config.UnverifiedAllow,
+		},
+		HealthCheckTimeout: time.Second,
+	}
+	authenticator := tokens.NewJWTAuthenticator(&conf.AuthConfig)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limiter := ratelimit.NewMemoryLimiter(ratelimit.Policy{
+		FreeAttempts: 100,
+		BaseDelay:    time.Second,
+		MaxAttempts:  200,
+		Lockout:      time.Minute,
+		Window:       time.Minute,
+	})
+
+	store := newMemoryStore()
+	userService := newUserService(store, authenticator, limiter)
+
+	s := &server{
+		config:        conf,
+		authenticator: authenticator,
+		limiter:       limiter,
+		logger:        logger,
+		handlers: handlers.Handlers{
+			UserHandler:   handlers.NewDefaultUserHandler(userService),
+			TaskHandler:   handlers.NewDefaultTaskHandler(services.NewDefaultTaskService(store.tasks)),
+			AdminHandler:  handlers.NewDefaultAdminHandler(services.NewDefaultAdminService(store.users, store.tokens, store.tasks, store.audit, audit.NewRepositoryRecorder(store.audit))),
+			OAuthHandler:  handlers.NewDefaultOAuthHandler(services.NewDefaultOAuthService(store.oauth, store.tokens, store.users, authenticator, config.UnverifiedAllow)),
+			HealthHandler: handlers.NewDefaultHealthHandler(health.NewChecker(time.Second)),
+		},
+	}
+	s.ready.Store(true)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := s.newApp()
+	go app.Listener(listener)
+	t.Cleanup(func() {
+		app.Shutdown()
+	})
+
+	return "http://" + listener.Addr().String(), authenticator
+}
+
+func TestClientEndToEnd(t *testing.T) {
+	url, authenticator := startServer(t)
+	ctx := context.Background()
+
+	var saved []models.TokenGroup
+	c := client.NewClient(&client.Config{
+		BaseURL: url,
+		OnTokens: func(tokens models.TokenGroup) {
+			saved = append(saved, tokens)
+		},
+	})
+
+	t.Run("register", func(t *testing.T) {
+		err := c.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "other", Password: "password1"})
+		if !errors.Is(err, client.ErrEmailTaken) {
+			t.Fatalf("Expected %v, got %v", client.ErrEmailTaken, err)
+		}
+
+		err = c.Register(ctx, models.RegistrationsPayload{Email: "invalid", Username: "", Password: "password1"})
+		var apiError *client.Error
+		if !errors.As(err, &apiError) || !errors.Is(err, client.ErrValidationFailed) || len(apiError.Fields) != 2 {
+			t.Fatalf("Expected errors of 2 fields, got %v", err)
+		}
+
+		err = c.Register(ctx, models.RegistrationsPayload{Email: "weak@example.com", Username: "weak", Password: "password"})
+		if !errors.Is(err, client.ErrWeakPassword) {
+			t.Fatalf("Expected %v, got %v", client.ErrWeakPassword, err)
+		}
+	})
+
+	t.Run("login", func(t *testing.T) {
+		_, err := c.Login(ctx, models.LoginPayload{Identifier: "user", Password: "wrong-password1"})
+		if !errors.Is(err, client.ErrInvalidCredentials) {
+			t.Fatalf("Expected %v, got %v", client.ErrInvalidCredentials, err)
+		}
+
+		if _, err = c.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(saved) != 1 {
+			t.Fatalf("Expected the tokens to be saved, got %d", len(saved))
+		}
+	})
+
+	var task *models.TaskPayload
+	t.Run("tasks", func(t *testing.T) {
+		date := models.ISOTime{Time: time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)}
+		var err error
+		task, err = c.AddTask(ctx, models.NewTaskPayload{Name: "Write tests", Description: "End to end", Priority: "High", Date: date})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = c.AddTask(ctx, models.NewTaskPayload{Name: "Invalid", Description: "Invalid", Priority: "Unknown", Date: date})
+		if !errors.Is(err, client.ErrInvalidPriority) {
+			t.Fatalf("Expected %v, got %v", client.ErrInvalidPriority, err)
+		}
+
+		task.Priority = "Low"
+		if err = c.UpdateTask(ctx, *task); err != nil {
+			t.Fatal(err)
+		}
+
+		tasks, err := c.GetTasks(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != 1 || tasks[0].Priority != "Low" || !tasks[0].Date.Equal(date.Time) {
+			t.Fatalf("Expected the updated task, got %v", tasks)
+		}
+	})
+
+	t.Run("tasks of other users", func(t *testing.T) {
+		other := client.NewClient(&client.Config{BaseURL: url})
+		err := other.Register(ctx, models.RegistrationsPayload{Email: "other@example.com", Username: "other", Password: "password1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = other.Login(ctx, models.LoginPayload{Identifier: "other", Password: "password1"}); err != nil {
+			t.Fatal(err)
+		}
+
+		changed := *task
+		changed.Name = "Changed by other user"
+		if err = other.UpdateTask(ctx, changed); !errors.Is(err, client.ErrTaskNotFound) {
+			t.Fatalf("Expected %v, got %v", client.ErrTaskNotFound, err)
+		}
+		if err = other.DeleteTask(ctx, task.Id); !errors.Is(err, client.ErrTaskNotFound) {
+			t.Fatalf("Expected %v, got %v", client.ErrTaskNotFound, err)
+		}
+
+		tasks, err := c.GetTasks(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != 1 || tasks[0].Name != task.Name {
+			t.Fatalf("Expected the task to be unchanged, got %v", tasks)
+		}
+	})
+
+	t.Run("refresh expired token", func(t *testing.T) {
+		current := c.Tokens()
+		expired, err := authenticator.CreateAccessToken(tokens.AccessClaims{UserId: 1, SessionId: uuid.New()}, time.Now().Add(-time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetTokens(models.TokenGroup{AccessToken: expired, RefreshToken: current.RefreshToken})
+
+		if _, err = c.GetTasks(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if c.Tokens().RefreshToken == current.RefreshToken {
+			t.Fatal("Expected the tokens to be refreshed")
+		}
+	})
+
+	t.Run("refresh rejected token", func(t *testing.T) {
+		current := c.Tokens()
+		c.SetTokens(models.TokenGroup{AccessToken: "invalid", RefreshToken: current.RefreshToken})
+
+		if err := c.DeleteTask(ctx, task.Id); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.DeleteTask(ctx, task.Id); !errors.Is(err, client.ErrTaskNotFound) {
+			t.Fatalf("Expected %v, got %v", client.ErrTaskNotFound, err)
+		}
+	})
+
+	t.Run("session expired", func(t *testing.T) {
+		c.SetTokens(models.TokenGroup{AccessToken: "invalid", RefreshToken: "invalid"})
+
+		if _, err := c.GetTasks(ctx); !errors.Is(err, client.ErrInvalidToken) {
+			t.Fatalf("Expected %v, got %v", client.ErrInvalidToken, err)
+		}
+	})
+}
+
+func TestLoginLockoutIsPerAccount(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter(ratelimit.Policy{
+		FreeAttempts: 2,
+		BaseDelay:    time.Minute,
+		MaxAttempts:  10,
+		Lockout:      time.Hour,
+		Window:       time.Hour,
+	})
+	authenticator := tokens.NewJWTAuthenticator(&config.AuthConfig{JwtSecret: []byte("secret"), JwtIssuer: "test"})
+	userService := newUserService(newMemoryStore(), authenticator, limiter)
+	ctx := context.Background()
+
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The attempts by email and by username are counted together.
+	for _, identifier := range []string{"user@example.com", "USER", "user"} {
+		_, err = userService.Login(ctx, models.LoginPayload{Identifier: identifier, Password: "wrong-password1"})
+		if err == nil || err.Code != utils.CodeInvalidCredentials {
+			t.Fatalf("Expected invalid credentials for %q, got %v", identifier, err)
+		}
+	}
+
+	_, err = userService.Login(ctx, models.LoginPayload{Identifier: "User@Example.com", Password: "password1"})
+	if err == nil || err.Code != utils.CodeTooManyRequests || err.RetryAfter <= 0 {
+		t.Fatalf("Expected the account to be locked, got %v", err)
+	}
+
+	// Unknown accounts are locked the same way, so the lockout doesn't reveal which accounts exist.
+	for range 3 {
+		_, err = userService.Login(ctx, models.LoginPayload{Identifier: "unknown", Password: "wrong-password1"})
+	}
+	if err == nil || err.Code != utils.CodeInvalidCredentials {
+		t.Fatalf("Expected invalid credentials, got %v", err)
+	}
+	_, err = userService.Login(ctx, models.LoginPayload{Identifier: "unknown", Password: "wrong-password1"})
+	if err == nil || err.Code != utils.CodeTooManyRequests {
+		t.Fatalf("Expected the unknown account to be locked, got %v", err)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	for _, payload := range []models.RegistrationsPayload{
+		{Email: "user@example.com", Username: "user", Password: "password1"},
+		{Email: "other@example.com", Username: "other", Password: "password1"},
+	} {
+		if err := userService.Register(ctx, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.users.users[0].EmailVerified = true
+	store.users.missChecks = true
+	token := userToken(1)
+
+	err := userService.ChangeEmail(ctx, token, models.ChangeEmailPayload{Email: "User@Example.com", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user := store.users.users[0]; user.Email != "User@Example.com" || !user.EmailVerified {
+		t.Fatalf("Expected the verified email with the new case, got %+v", user)
+	}
+
+	// The email taken after the check is a conflict, not a server error.
+	err = userService.ChangeEmail(ctx, token, models.ChangeEmailPayload{Email: "OTHER@example.com", Password: "password1"})
+	if err == nil || err.Code != utils.CodeEmailTaken || err.Status != http.StatusConflict {
+		t.Fatalf("Expected the email to be taken, got %v", err)
+	}
+	if user := store.users.users[0]; user.Email != "User@Example.com" || !user.EmailVerified {
+		t.Fatalf("Expected the email to be unchanged, got %+v", user)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"net/http/httptest"
+	"server/client"
+	"server/handlers"
+	"server/models"
+	"server/utils"
+	"strings"
+	"testing"
+)
+
+// doRequest will send the request with the body and decode the json response into result.
+// It returns the status of the response.
+func doRequest(t *testing.T, method, url, accessToken, contentType, body string, result any) int {
+	t.Helper()
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(fiber.HeaderContentType, contentType)
+	if accessToken != "" {
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer "+accessToken)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	_ = json.NewDecoder(response.Body).Decode(result)
+	return response.StatusCode
+}
+
+// send will send the json body and return the status and the error code of the response.
+func send(t *testing.T, method, url, accessToken, body string) (int, string) {
+	t.Helper()
+	var errorResponse utils.ErrorResponse
+	status := doRequest(t, method, url, accessToken, fiber.MIMEApplicationJSON, body, &errorResponse)
+	return status, errorResponse.Code
+}
+
+func TestMalformedPayload(t *testing.T) {
+	url, _ := startServer(t)
+	ctx := context.Background()
+
+	c := client.NewClient(&client.Config{BaseURL: url})
+	err := c.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenGroup, err := c.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"truncated json", http.MethodPost, "/api/v1/users/register", `{"email": "other@example.com", "user`},
+		{"wrong type", http.MethodPost, "/api/v1/users/register", `{"email": 1}`},
+		{"invalid time", http.MethodPost, "/api/v1/tasks/add", `{"name": "Task", "priority": "Low", "date": "tomorrow"}`},
+		{"invalid query", http.MethodGet, "/api/v1/users/me/activity?limit=many", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, code := send(t, test.method, url+test.path, tokenGroup.AccessToken, test.body)
+			if status != http.StatusBadRequest || code != utils.CodeValidationFailed {
+				t.Fatalf("Expected %d %s, got %d %s", http.StatusBadRequest, utils.CodeValidationFailed, status, code)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerMalformedPayload(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+	app.Post("/", func(c *fiber.Ctx) error {
+		var payload models.RegistrationsPayload
+		return c.BodyParser(&payload)
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"email": `))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errorResponse utils.ErrorResponse
+	if err = json.NewDecoder(response.Body).Decode(&errorResponse); err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusBadRequest || errorResponse.Code != utils.CodeValidationFailed {
+		t.Fatalf("Expected the parse error to be a client error, got %d %s", response.StatusCode, errorResponse.Code)
+	}
+}
+
+func TestHandlersWithoutClaims(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+	app.Post("/refresh", handlers.NewDefaultUserHandler(nil).Refresh())
+	app.Get("/tasks", handlers.NewDefaultTaskHandler(nil).GetTasks())
+	app.Post("/tasks", handlers.NewDefaultTaskHandler(nil).AddTask())
+
+	// The handlers used without the middleware respond with an error instead of dereferencing missing claims.
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/refresh"},
+		{http.MethodGet, "/tasks"},
+		{http.MethodPost, "/tasks"},
+	} {
+		response, err := app.Test(httptest.NewRequest(route.method, route.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("Expected status %d for %s %s, got %d", http.StatusInternalServerError, route.method, route.path, response.StatusCode)
+		}
+	}
+}
@@ -1,71 +1,484 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/gofiber/fiber/v2"
-	"log"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"server/audit"
+	"server/auth/oidc"
+	"server/auth/passwords"
 	"server/auth/tokens"
 	"server/config"
 	"server/database"
 	"server/handlers"
+	"server/health"
+	"server/janitor"
+	"server/logging"
+	"server/mail"
+	"server/metrics"
+	"server/migrations"
+	"server/models"
+	"server/ratelimit"
 	"server/repositories"
 	"server/services"
+	"server/tracing"
+	"server/utils"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 type server struct {
 	config        *config.Config
 	handlers      handlers.Handlers
 	authenticator *tokens.JWTAuthenticator
+	limiter       ratelimit.Limiter
+	logger        *slog.Logger
+	// ready is true while the server accepts requests. It is false before the server listens and while it drains.
+	ready atomic.Bool
 }
 
-func (s *server) start() error {
-	app := fiber.New()
+func (s *server) newApp() *fiber.App {
+	app := fiber.New(fiber.Config{
+		ProxyHeader: s.config.ProxyHeader,
+		// The proxy header is read only from the trusted proxies, so clients can't choose their ip.
+		EnableTrustedProxyCheck: s.config.ProxyHeader != "",
+		TrustedProxies:          s.config.TrustedProxies,
+		ErrorHandler:            handlers.ErrorHandler,
+	})
+	app.Hooks().OnListen(func(fiber.ListenData) error {
+		s.ready.Store(true)
+		return nil
+	})
+
+	// Probes are registered before the middleware, so they skip it.
+	app.Get("/healthz", s.handlers.HealthHandler.Liveness())
+	app.Get("/readyz", s.handlers.HealthHandler.Readiness())
+	if s.config.MetricsConfig.Enabled() && s.config.MetricsConfig.Addr == "" {
+		app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler(s.config.MetricsConfig.Token)))
+	}
+
+	app.Use(metrics.Middleware())
+	app.Use(tracing.Middleware())
+	app.Use(logging.Middleware(s.logger))
+
+	// Clients with persistent connections are asked to reconnect, so they reach another replica while this one drains.
+	app.Use(func(c *fiber.Ctx) error {
+		if !s.ready.Load() {
+			c.Set(fiber.HeaderConnection, "close")
+		}
+		return c.Next()
+	})
+	app.Use(handlers.ClientInfoMiddleware())
+
 	api := app.Group("/api")
 	api1 := api.Group("/v1")
 
 	// User routes
 	userRouter := api1.Group("/users")
-	userRouter.Post("/register", s.handlers.UserHandler.Register())
-	userRouter.Post("/login", s.handlers.UserHandler.Login())
-	userRouter.Get("/refresh", s.authenticator.Middleware(tokens.RefreshTokenType), s.handlers.UserHandler.Refresh())
+	userRouter.Post(
+		"/register",
+		handlers.RateLimitMiddleware(s.limiter, "register", true),
+		s.handlers.UserHandler.Register(),
+	)
+	userRouter.Post(
+		"/login",
+		handlers.RateLimitMiddleware(s.limiter, "login", false),
+		s.handlers.UserHandler.Login(),
+	)
+	userRouter.Get(
+		"/refresh",
+		handlers.RateLimitMiddleware(s.limiter, "refresh", false),
+		s.authenticator.Middleware(tokens.RefreshTokenType),
+		s.handlers.UserHandler.Refresh(),
+	)
+	userRouter.Post(
+		"/password/forgot",
+		handlers.RateLimitMiddleware(s.limiter, "forgot_password", true),
+		s.handlers.UserHandler.ForgotPassword(),
+	)
+	userRouter.Post(
+		"/password/reset",
+		handlers.RateLimitMiddleware(s.limiter, "reset_password", true),
+		s.handlers.UserHandler.ResetPassword(),
+	)
+	userRouter.Post(
+		"/email/verify",
+		handlers.RateLimitMiddleware(s.limiter, "verify_email", true),
+		s.handlers.UserHandler.VerifyEmail(),
+	)
+	userRouter.Post(
+		"/email/resend",
+		handlers.RateLimitMiddleware(s.limiter, "resend_verification", true),
+		s.handlers.UserHandler.ResendVerification(),
+	)
+
+	if s.handlers.OIDCHandler != nil {
+		userRouter.Get("/oidc/login", s.handlers.OIDCHandler.Login())
+		userRouter.Get(
+			"/oidc/callback",
+			handlers.RateLimitMiddleware(s.limiter, "oidc", false),
+			s.handlers.OIDCHandler.Callback(),
+		)
+	}
+
+	// Account routes of the authenticated user
+	meRouter := userRouter.Group(
+		"/me",
+		s.authenticator.Middleware(tokens.AccessTokenType),
+		s.authenticator.FirstPartyMiddleware(),
+	)
+	meRouter.Put("/password", s.handlers.UserHandler.ChangePassword())
+	meRouter.Put("/email", s.handlers.UserHandler.ChangeEmail())
+	meRouter.Put("/username", s.handlers.UserHandler.ChangeUsername())
+	meRouter.Delete("", s.handlers.UserHandler.DeleteAccount())
+	meRouter.Get("/export", s.handlers.UserHandler.ExportData())
+	meRouter.Get("/activity", s.handlers.UserHandler.GetActivity())
 
 	// Task routes
-	taskRouter := api1.Group("/tasks", s.authenticator.Middleware(tokens.AccessTokenType))
+	taskRouter := api1.Group(
+		"/tasks",
+		s.authenticator.Middleware(tokens.AccessTokenType),
+		s.authenticator.ScopeMiddleware(models.ScopeTasksRead, models.ScopeTasksWrite),
+		s.authenticator.WriteAccessMiddleware(),
+	)
 	taskRouter.Get("/get", s.handlers.TaskHandler.GetTasks())
 	taskRouter.Post("/add", s.handlers.TaskHandler.AddTask())
 	taskRouter.Put("/update", s.handlers.TaskHandler.UpdateTask())
 	taskRouter.Delete("/delete/:id", s.handlers.TaskHandler.DeleteTask())
 
-	return app.Listen(s.config.ServerAddr)
+	// Admin routes
+	adminRouter := api1.Group(
+		"/admin",
+		s.authenticator.Middleware(tokens.AccessTokenType),
+		s.authenticator.FirstPartyMiddleware(),
+		s.authenticator.RoleMiddleware(string(models.RoleAdmin)),
+	)
+	adminRouter.Get("/users", s.handlers.AdminHandler.SearchUsers())
+	adminRouter.Put("/users/:id/disable", s.handlers.AdminHandler.DisableUser())
+	adminRouter.Put("/users/:id/enable", s.handlers.AdminHandler.EnableUser())
+	adminRouter.Post("/users/:id/logout", s.handlers.AdminHandler.LogoutUser())
+	adminRouter.Get("/users/:id/tasks/count", s.handlers.AdminHandler.GetTaskCounts())
+	adminRouter.Get("/audit", s.handlers.AdminHandler.SearchAuditEvents())
+
+	// OAuth routes
+	oauthRouter := api1.Group("/oauth")
+	oauthRouter.Post(
+		"/token",
+		handlers.RateLimitMiddleware(s.limiter, "oauth_token", false),
+		s.handlers.OAuthHandler.Token(),
+	)
+
+	// The consent screen and client management are used only by the user.
+	authorizeRouter := oauthRouter.Group(
+		"/authorize",
+		s.authenticator.Middleware(tokens.AccessTokenType),
+		s.authenticator.FirstPartyMiddleware(),
+	)
+	authorizeRouter.Get("", s.handlers.OAuthHandler.GetConsent())
+	authorizeRouter.Post("", s.handlers.OAuthHandler.Authorize())
+
+	clientRouter := oauthRouter.Group(
+		"/clients",
+		s.authenticator.Middleware(tokens.AccessTokenType),
+		s.authenticator.FirstPartyMiddleware(),
+	)
+	clientRouter.Get("", s.handlers.OAuthHandler.GetClients())
+	clientRouter.Post("", s.handlers.OAuthHandler.RegisterClient())
+	clientRouter.Delete("/:id", s.handlers.OAuthHandler.DeleteClient())
+
+	return app
+}
+
+// readyCheck will return an error while the server is not listening or is draining.
+func (s *server) readyCheck(context.Context) error {
+	if !s.ready.Load() {
+		return errors.New("not accepting requests")
+	}
+	return nil
+}
+
+// run will serve the app until the context is canceled and then drain it.
+// The server is marked not ready and waits for the shutdown delay before it stops accepting connections,
+// so the load balancer stops sending new requests first. In-flight requests get the shutdown timeout to finish.
+func (s *server) run(ctx context.Context, app *fiber.App) error {
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(s.config.ServerAddr)
+	}()
+
+	select {
+	case err := <-listenErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down server")
+	s.ready.Store(false)
+	time.Sleep(s.config.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+	if err := app.ShutdownWithContext(shutdownCtx); err != nil {
+		return err
+	}
+
+	return <-listenErr
+}
+
+// purgeDeletedUsers will periodically delete the users whose deletion grace period has passed
+// until the context is canceled.
+func purgeDeletedUsers(ctx context.Context, userRepository repositories.UserRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := userRepository.DeleteScheduledUsers(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Error deleting scheduled users", "error", err)
+				continue
+			}
+
+			if count > 0 {
+				slog.InfoContext(ctx, "Deleted scheduled users", "count", count)
+			}
+		}
+	}
+}
+
+// fatal will log the error and exit.
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
 }
 
 func main() {
-	conf := config.NewConfig()
+	args, err := config.ParseArgs(os.Args[1:])
+	if err != nil {
+		fatal("Error parsing arguments", err)
+	}
+	conf, err := config.NewConfig(args)
+	if err == nil {
+		err = conf.Validate()
+	}
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+	if args.PrintConfig {
+		if err = conf.Print(os.Stdout); err != nil {
+			fatal("Error printing configuration", err)
+		}
+		return
+	}
+
+	logger, err := logging.New(&conf.LogConfig, os.Stdout)
+	if err != nil {
+		fatal("Error creating logger", err)
+	}
+	slog.SetDefault(logger)
+	if secrets := conf.DefaultSecrets(); len(secrets) > 0 {
+		slog.Warn("Insecure default secrets are used, they are refused in production", "settings", secrets)
+	}
+
+	utils.SetErrorFormat(utils.ErrorFormat(conf.ErrorFormat))
+
 	authenticator := tokens.NewJWTAuthenticator(&conf.AuthConfig)
 	db, err := database.Connect(&conf.DatabaseConfig)
 	if err != nil {
-		log.Fatalf("Error creating database connection: %v", err)
+		fatal("Error creating database connection", err)
 	}
+	metrics.RegisterDB(db, "postgres")
+
+	if conf.MigrateOnStart {
+		migrator, err := migrations.NewMigrator(db, migrations.FS)
+		if err != nil {
+			fatal("Error loading migrations", err)
+		}
+		if err = migrator.Up(context.Background()); err != nil {
+			fatal("Error applying migrations", err)
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), &conf.TracingConfig)
+	if err != nil {
+		fatal("Error setting up tracing", err)
+	}
+
+	mailer, err := mail.NewMailer(&conf.MailConfig)
+	if err != nil {
+		fatal("Error creating mailer", err)
+	}
+
+	hasher, err := passwords.NewHasherFromConfig(&conf.PasswordConfig)
+	if err != nil {
+		fatal("Error creating password hasher", err)
+	}
+
+	var breachedChecker passwords.BreachedChecker
+	if conf.PasswordPolicyConfig.BreachedPasswordsPath != "" {
+		breachedChecker = passwords.NewRangeChecker(conf.PasswordPolicyConfig.BreachedPasswordsPath)
+	}
+	passwordPolicy := passwords.NewPolicy(&conf.PasswordPolicyConfig, breachedChecker)
+
+	userRepository := repositories.NewPostgresUserRepository(db)
+	tokenRepository := repositories.NewPostgresTokenRepository(db)
+	taskRepository := repositories.NewPostgresTaskRepository(db)
+	auditRepository := repositories.NewPostgresAuditRepository(db)
+	recorder := metrics.NewCountingRecorder(audit.NewRepositoryRecorder(auditRepository))
+	limiter := ratelimit.NewPostgresLimiter(db, ratelimit.NewPolicy(&conf.RateLimitConfig))
+
+	userService := services.NewDefaultUserService(
+		userRepository,
+		tokenRepository,
+		repositories.NewPostgresPasswordResetRepository(db),
+		repositories.NewPostgresEmailVerificationRepository(db),
+		repositories.NewPostgresIdentityRepository(db),
+		taskRepository,
+		auditRepository,
+		recorder,
+		mailer,
+		authenticator,
+		hasher,
+		passwordPolicy,
+		limiter,
+		conf.AuthConfig.UnverifiedPolicy,
+		conf.AccountConfig.DeletionGracePeriod,
+	)
 
 	s := &server{
+		logger:        logger,
 		authenticator: authenticator,
 		config:        conf,
+		limiter:       limiter,
 		handlers: handlers.Handlers{
-			UserHandler: handlers.NewDefaultUserHandler(
-				services.NewDefaultUserService(
-					repositories.NewPostgresUserRepository(db),
-					repositories.NewPostgresTokenRepository(db),
+			UserHandler: handlers.NewDefaultUserHandler(userService),
+			TaskHandler: handlers.NewDefaultTaskHandler(
+				services.NewDefaultTaskService(
+					taskRepository,
+				),
+			),
+			OAuthHandler: handlers.NewDefaultOAuthHandler(
+				services.NewDefaultOAuthService(
+					repositories.NewPostgresOAuthRepository(db),
+					tokenRepository,
+					userRepository,
 					authenticator,
+					conf.AuthConfig.UnverifiedPolicy,
 				),
 			),
-			TaskHandler: handlers.NewDefaultTaskHandler(
-				services.NewDefaultTaskService(
-					repositories.NewPostgresTaskRepository(db),
+			AdminHandler: handlers.NewDefaultAdminHandler(
+				services.NewDefaultAdminService(
+					userRepository,
+					tokenRepository,
+					taskRepository,
+					auditRepository,
+					recorder,
 				),
 			),
 		},
 	}
 
-	if err = s.start(); err != nil {
-		log.Fatal("Error starting server", err)
+	expectedVersion, err := migrations.LatestVersion()
+	if err != nil {
+		fatal("Error reading migrations", err)
+	}
+	s.handlers.HealthHandler = handlers.NewDefaultHealthHandler(
+		health.NewChecker(
+			conf.HealthCheckTimeout,
+			health.Check{Name: "server", Func: s.readyCheck},
+			health.DatabaseCheck(db),
+			health.MigrationCheck(db, expectedVersion),
+		),
+	)
+
+	if conf.OIDCConfig.Enabled() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		provider, err := oidc.NewProvider(ctx, &conf.OIDCConfig, &http.Client{Timeout: time.Second * 10})
+		cancel()
+		if err != nil {
+			fatal("Error creating OIDC provider", err)
+		}
+		s.handlers.OIDCHandler = handlers.NewDefaultOIDCHandler(provider, userService, conf.AuthConfig.JwtSecret)
+	}
+
+	// Background workers run until the server is drained.
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		purgeDeletedUsers(workersCtx, userRepository, conf.AccountConfig.DeletionPurgeInterval)
+	}()
+
+	if conf.JanitorConfig.Enabled {
+		tokenJanitor := janitor.NewJanitor(
+			tokenRepository,
+			limiter,
+			janitor.NewPostgresLocker(db, janitor.LockKey),
+			conf.JanitorConfig.Interval,
+			conf.JanitorConfig.BatchSize,
+		)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			tokenJanitor.Run(workersCtx)
+		}()
+	}
+
+	// The metrics are served separately if the address is set, so they don't have to be exposed with the api.
+	var metricsServer *http.Server
+	if conf.MetricsConfig.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler(conf.MetricsConfig.Token))
+		metricsServer = &http.Server{Addr: conf.MetricsConfig.Addr, Handler: mux, ReadHeaderTimeout: time.Second * 10}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Error serving metrics", "error", err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = s.run(ctx, s.newApp())
+
+	// The metrics are served until the api is drained, so the drain can be observed.
+	if metricsServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		if closeErr := metricsServer.Shutdown(shutdownCtx); closeErr != nil {
+			slog.Error("Error stopping metrics server", "error", closeErr)
+		}
+		cancel()
+	}
+
+	// The workers are stopped after the requests are drained and before the database they use is closed.
+	stopWorkers()
+	workers.Wait()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	if closeErr := shutdownTracing(shutdownCtx); closeErr != nil {
+		slog.Error("Error flushing spans", "error", closeErr)
+	}
+	cancel()
+
+	if closeErr := db.Close(); closeErr != nil {
+		slog.Error("Error closing database connection", "error", closeErr)
+	}
+
+	if err != nil {
+		fatal("Error running server", err)
 	}
+	slog.Info("Server stopped")
 }
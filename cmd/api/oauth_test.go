@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	neturl "net/url"
+	"server/client"
+	"server/models"
+	"server/utils"
+	"strings"
+	"testing"
+)
+
+const (
+	redirectURI  = "https://app.example.com/callback"
+	codeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+// oauthFlow sends the requests of the authorization code flow of a user to the server.
+type oauthFlow struct {
+	t           *testing.T
+	url         string
+	accessToken string
+}
+
+// registerClient will register a public client with the redirect uri and return its id.
+func (f *oauthFlow) registerClient(name string) string {
+	body, _ := json.Marshal(models.RegisterClientPayload{Name: name, RedirectURIs: []string{redirectURI}})
+	var registered models.RegisteredClient
+	status := doRequest(f.t, http.MethodPost, f.url+"/api/v1/oauth/clients", f.accessToken, fiber.MIMEApplicationJSON, string(body), &registered)
+	if status != http.StatusCreated {
+		f.t.Fatalf("Expected the client to be registered, got %d", status)
+	}
+	return registered.Id
+}
+
+// authorize will approve the authorization request of the client and return the issued code.
+func (f *oauthFlow) authorize(clientId string) string {
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	body, _ := json.Marshal(models.AuthorizePayload{
+		AuthorizeQuery: models.AuthorizeQuery{
+			ResponseType:        "code",
+			ClientId:            clientId,
+			RedirectURI:         redirectURI,
+			Scope:               models.ScopeTasksRead,
+			State:               "state",
+			CodeChallenge:       base64.RawURLEncoding.EncodeToString(challenge[:]),
+			CodeChallengeMethod: "S256",
+		},
+		Approve: true,
+	})
+
+	var result models.AuthorizeResult
+	status := doRequest(f.t, http.MethodPost, f.url+"/api/v1/oauth/authorize", f.accessToken, fiber.MIMEApplicationJSON, string(body), &result)
+	if status != http.StatusOK {
+		f.t.Fatalf("Expected the request to be authorized, got %d", status)
+	}
+
+	redirect, err := neturl.Parse(result.RedirectURI)
+	if err != nil || redirect.Query().Get("state") != "state" || redirect.Query().Get("code") == "" {
+		f.t.Fatalf("Expected the redirect with the code and state, got %q", result.RedirectURI)
+	}
+	return redirect.Query().Get("code")
+}
+
+// token will send the token request and return the tokens or the oauth error.
+func (f *oauthFlow) token(params neturl.Values) (*models.OAuthTokenResponse, string) {
+	var response struct {
+		models.OAuthTokenResponse
+		models.OAuthError
+	}
+	status := doRequest(f.t, http.MethodPost, f.url+"/api/v1/oauth/token", "", fiber.MIMEApplicationForm, params.Encode(), &response)
+	if status != http.StatusOK {
+		return nil, response.Code
+	}
+	return &response.OAuthTokenResponse, ""
+}
+
+// exchangeCode will exchange the code of the client with the redirect uri and the verifier.
+func (f *oauthFlow) exchangeCode(clientId, code, redirectURI, verifier string) (*models.OAuthTokenResponse, string) {
+	return f.token(neturl.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {clientId},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+}
+
+// refresh will exchange the refresh token of the client.
+func (f *oauthFlow) refresh(clientId, refreshToken string) (*models.OAuthTokenResponse, string) {
+	return f.token(neturl.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {clientId},
+		"refresh_token": {refreshToken},
+	})
+}
+
+func TestOAuthEndToEnd(t *testing.T) {
+	url, _ := startServer(t)
+	ctx := context.Background()
+
+	c := client.NewClient(&client.Config{BaseURL: url})
+	err := c.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenGroup, err := c.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flow := &oauthFlow{t: t, url: url, accessToken: tokenGroup.AccessToken}
+	clientId := flow.registerClient("Calendar sync")
+	otherClientId := flow.registerClient("Other app")
+
+	t.Run("invalid authorization request", func(t *testing.T) {
+		status, code := send(t, http.MethodGet, url+"/api/v1/oauth/authorize?response_type=code", tokenGroup.AccessToken, "")
+		if status != http.StatusBadRequest || code != utils.CodeValidationFailed {
+			t.Fatalf("Expected the missing client to be invalid, got %d %s", status, code)
+		}
+
+		status, code = send(t, http.MethodPost, url+"/api/v1/oauth/authorize", tokenGroup.AccessToken, `{"client_id": `)
+		if status != http.StatusBadRequest || code != utils.CodeValidationFailed {
+			t.Fatalf("Expected the malformed body to be invalid, got %d %s", status, code)
+		}
+	})
+
+	t.Run("wrong code verifier", func(t *testing.T) {
+		code := flow.authorize(clientId)
+		if _, oauthError := flow.exchangeCode(clientId, code, redirectURI, strings.Repeat("a", 43)); oauthError != "invalid_grant" {
+			t.Fatalf("Expected invalid_grant, got %q", oauthError)
+		}
+
+		// The code is consumed by the failed attempt, so the verifier can't be guessed.
+		if _, oauthError := flow.exchangeCode(clientId, code, redirectURI, codeVerifier); oauthError != "invalid_grant" {
+			t.Fatalf("Expected the code to be consumed, got %q", oauthError)
+		}
+	})
+
+	t.Run("wrong redirect uri", func(t *testing.T) {
+		code := flow.authorize(clientId)
+		if _, oauthError := flow.exchangeCode(clientId, code, "https://attacker.example.com/callback", codeVerifier); oauthError != "invalid_grant" {
+			t.Fatalf("Expected invalid_grant, got %q", oauthError)
+		}
+	})
+
+	var tokens *models.OAuthTokenResponse
+	t.Run("exchange code", func(t *testing.T) {
+		code := flow.authorize(clientId)
+		var oauthError string
+		tokens, oauthError = flow.exchangeCode(clientId, code, redirectURI, codeVerifier)
+		if oauthError != "" {
+			t.Fatalf("Expected the tokens, got %q", oauthError)
+		}
+		if tokens.Scope != models.ScopeTasksRead || tokens.TokenType != "Bearer" {
+			t.Fatalf("Expected the granted scope, got %+v", tokens)
+		}
+
+		if _, oauthError = flow.exchangeCode(clientId, code, redirectURI, codeVerifier); oauthError != "invalid_grant" {
+			t.Fatalf("Expected the code to be single-use, got %q", oauthError)
+		}
+
+		var tasks []models.TaskPayload
+		if status := doRequest(t, http.MethodGet, url+"/api/v1/tasks/get", tokens.AccessToken, "", "", &tasks); status != http.StatusOK {
+			t.Fatalf("Expected the access token to read the tasks, got %d", status)
+		}
+	})
+
+	t.Run("refresh token of another client", func(t *testing.T) {
+		if _, oauthError := flow.refresh(otherClientId, tokens.RefreshToken); oauthError != "invalid_grant" {
+			t.Fatalf("Expected invalid_grant, got %q", oauthError)
+		}
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		refreshed, oauthError := flow.refresh(clientId, tokens.RefreshToken)
+		if oauthError != "" {
+			t.Fatalf("Expected the tokens, got %q", oauthError)
+		}
+		if refreshed.Scope != models.ScopeTasksRead {
+			t.Fatalf("Expected the scope to be kept, got %q", refreshed.Scope)
+		}
+
+		// The refresh token is rotated.
+		if _, oauthError = flow.refresh(clientId, tokens.RefreshToken); oauthError != "invalid_grant" {
+			t.Fatalf("Expected the old refresh token to be invalid, got %q", oauthError)
+		}
+	})
+}
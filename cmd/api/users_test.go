@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"server/models"
+	"server/utils"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginWithIdentity(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	identity := models.Identity{Issuer: "https://idp.example.com", Subject: "1", Email: "new@example.com", PreferredUsername: "new"}
+	if _, err := userService.LoginWithIdentity(ctx, identity); err == nil || err.Code != utils.CodeIdentityEmailUnverified {
+		t.Fatalf("Expected the unverified email of the provider to be refused, got %v", err)
+	}
+
+	identity.EmailVerified = true
+	if _, err := userService.LoginWithIdentity(ctx, identity); err != nil {
+		t.Fatal(err)
+	}
+	user := store.users.users[0]
+	if user.Username != "new" || user.Password != "" || !user.EmailVerified {
+		t.Fatalf("Expected a verified user without password, got %+v", user)
+	}
+
+	// The next login uses the linked user.
+	if _, err := userService.LoginWithIdentity(ctx, identity); err != nil || len(store.users.users) != 1 {
+		t.Fatalf("Expected the linked user, got %v and %d users", err, len(store.users.users))
+	}
+}
+
+func TestLoginWithIdentityLinksVerifiedAccount(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.users.users[0].EmailVerified = true
+	password := store.users.users[0].Password
+
+	identity := models.Identity{Issuer: "https://idp.example.com", Subject: "1", Email: "USER@example.com", EmailVerified: true}
+	if _, err = userService.LoginWithIdentity(ctx, identity); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.users.users) != 1 || store.users.users[0].Password != password {
+		t.Fatalf("Expected the account to be linked with its password, got %+v", store.users.users)
+	}
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoginWithIdentityTakesOverUnverifiedAccount(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	// Someone registered the email of the victim before the victim and is logged in.
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "victim@example.com", Username: "attacker", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "attacker", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	identity := models.Identity{Issuer: "https://idp.example.com", Subject: "1", Email: "victim@example.com", EmailVerified: true}
+	if _, err = userService.LoginWithIdentity(ctx, identity); err != nil {
+		t.Fatal(err)
+	}
+
+	user := store.users.users[0]
+	if user.Password != "" || !user.EmailVerified {
+		t.Fatalf("Expected the verified account without the password, got %+v", user)
+	}
+	if len(store.tokens.tokens) != 1 {
+		t.Fatalf("Expected only the session of the identity login, got %d", len(store.tokens.tokens))
+	}
+	_, err = userService.Login(ctx, models.LoginPayload{Identifier: "attacker", Password: "password1"})
+	if err == nil || err.Code != utils.CodeInvalidCredentials {
+		t.Fatalf("Expected the old password to be refused, got %v", err)
+	}
+}
+
+func TestChangeUsernameTakenConcurrently(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	for _, payload := range []models.RegistrationsPayload{
+		{Email: "user@example.com", Username: "user", Password: "password1"},
+		{Email: "other@example.com", Username: "other", Password: "password1"},
+	} {
+		if err := userService.Register(ctx, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.users.missChecks = true
+
+	if err := userService.ChangeUsername(ctx, userToken(1), models.ChangeUsernamePayload{Username: "USER"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The username taken after the check is a conflict, not a server error.
+	err := userService.ChangeUsername(ctx, userToken(1), models.ChangeUsernamePayload{Username: "Other"})
+	if err == nil || err.Code != utils.CodeUsernameTaken || err.Status != http.StatusConflict {
+		t.Fatalf("Expected the username to be taken, got %v", err)
+	}
+	if user := store.users.users[0]; user.Username != "USER" {
+		t.Fatalf("Expected the username to be unchanged, got %q", user.Username)
+	}
+}
+
+func TestRegisterTakenConcurrently(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.users.missChecks = true
+
+	tests := []struct {
+		payload models.RegistrationsPayload
+		code    string
+	}{
+		{models.RegistrationsPayload{Email: "USER@example.com", Username: "other", Password: "password1"}, utils.CodeEmailTaken},
+		{models.RegistrationsPayload{Email: "other@example.com", Username: "User", Password: "password1"}, utils.CodeUsernameTaken},
+	}
+	for _, test := range tests {
+		err = userService.Register(ctx, test.payload)
+		if err == nil || err.Code != test.code || err.Status != http.StatusConflict {
+			t.Fatalf("Expected %s, got %v", test.code, err)
+		}
+	}
+
+	// The username chosen for the identity can be taken after the check too.
+	identity := models.Identity{Issuer: "https://idp.example.com", Subject: "1", Email: "new@example.com", EmailVerified: true, PreferredUsername: "user"}
+	if _, err = userService.LoginWithIdentity(ctx, identity); err == nil || err.Code != utils.CodeUsernameTaken {
+		t.Fatalf("Expected %s, got %v", utils.CodeUsernameTaken, err)
+	}
+}
+
+func TestForgotPasswordMailerFailure(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.mailer.err = errors.New("mail server is down")
+
+	// The response is the same for registered and unknown emails.
+	for _, email := range []string{"user@example.com", "unknown@example.com"} {
+		if err = userService.ForgotPassword(ctx, models.ForgotPasswordPayload{Email: email}); err != nil {
+			t.Fatalf("Expected no error for %q, got %v", email, err)
+		}
+	}
+}
+
+func TestResendVerificationMailerFailure(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.mailer.err = errors.New("mail server is down")
+
+	// The response is the same for registered and unknown emails.
+	for _, email := range []string{"user@example.com", "unknown@example.com"} {
+		if err = userService.ResendVerification(ctx, models.ResendVerificationPayload{Email: email}); err != nil {
+			t.Fatalf("Expected no error for %q, got %v", email, err)
+		}
+	}
+}
+
+func TestLoginWithIdentityUsername(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	tests := []struct {
+		name              string
+		preferredUsername string
+		email             string
+		expected          string
+	}{
+		{"preferred username", "Jane Doe", "jane@example.com", "JaneDoe"},
+		{"too long", long, "long@example.com", long[:models.MaxUsernameLength-2]},
+		{"blank", " \t", "blank@example.com", "blank"},
+		{"invalid", "@", "@example.com", "user"},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newMemoryStore()
+			userService := newTestUserService(store)
+
+			identity := models.Identity{Issuer: "https://idp.example.com", Subject: strconv.Itoa(i), Email: test.email, EmailVerified: true, PreferredUsername: test.preferredUsername}
+			if _, err := userService.LoginWithIdentity(context.Background(), identity); err != nil {
+				t.Fatal(err)
+			}
+			if username := store.users.users[0].Username; username != test.expected {
+				t.Fatalf("Expected %q, got %q", test.expected, username)
+			}
+		})
+	}
+}
+
+// resetToken will return the reset token from the last email sent to the user.
+func resetToken(t *testing.T, store *memoryStore) string {
+	t.Helper()
+	message := store.mailer.messages[len(store.mailer.messages)-1]
+	_, token, ok := strings.Cut(message.Body, "reset your password: ")
+	if !ok {
+		t.Fatalf("Expected the reset token in the email, got %q", message.Body)
+	}
+	token, _, _ = strings.Cut(token, "\n")
+	return token
+}
+
+func TestResetPassword(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the latest reset token is valid.
+	if err = userService.ForgotPassword(ctx, models.ForgotPasswordPayload{Email: "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	previous := resetToken(t, store)
+	if err = userService.ForgotPassword(ctx, models.ForgotPasswordPayload{Email: "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	token := resetToken(t, store)
+	err = userService.ResetPassword(ctx, models.ResetPasswordPayload{Token: previous, Password: "password2"})
+	if err == nil || err.Code != utils.CodeInvalidResetToken {
+		t.Fatalf("Expected the previous token to be invalid, got %v", err)
+	}
+
+	// The weak password doesn't consume the token.
+	err = userService.ResetPassword(ctx, models.ResetPasswordPayload{Token: token, Password: "password"})
+	if err == nil || err.Code != utils.CodeWeakPassword {
+		t.Fatalf("Expected the weak password to be refused, got %v", err)
+	}
+	if err = userService.ResetPassword(ctx, models.ResetPasswordPayload{Token: token, Password: "password2"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.tokens.tokens) != 0 {
+		t.Fatalf("Expected the sessions to be revoked, got %d", len(store.tokens.tokens))
+	}
+
+	// The token can be used only once.
+	err = userService.ResetPassword(ctx, models.ResetPasswordPayload{Token: token, Password: "password3"})
+	if err == nil || err.Code != utils.CodeInvalidResetToken {
+		t.Fatalf("Expected the used token to be invalid, got %v", err)
+	}
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password2"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+	ctx := context.Background()
+
+	err := userService.Register(ctx, models.RegistrationsPayload{Email: "user@example.com", Username: "user", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = userService.ForgotPassword(ctx, models.ForgotPasswordPayload{Email: "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	token := resetToken(t, store)
+	for tokenHash, reset := range store.resets.tokens {
+		reset.exp = time.Now().Add(-time.Second)
+		store.resets.tokens[tokenHash] = reset
+	}
+
+	err = userService.ResetPassword(ctx, models.ResetPasswordPayload{Token: token, Password: "password2"})
+	if err == nil || err.Code != utils.CodeInvalidResetToken {
+		t.Fatalf("Expected the expired token to be invalid, got %v", err)
+	}
+	if _, err = userService.Login(ctx, models.LoginPayload{Identifier: "user", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateVerifiedUser(t *testing.T) {
+	store := newMemoryStore()
+	userService := newTestUserService(store)
+
+	payload := models.RegistrationsPayload{Email: "admin@example.com", Username: "admin", Password: "password1"}
+	userId, err := userService.CreateUser(context.Background(), payload, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user := store.users.users[0]; user.Id != userId || !user.EmailVerified {
+		t.Fatalf("Expected the verified user %d, got %+v", userId, user)
+	}
+	if len(store.mailer.messages) != 0 {
+		t.Fatalf("Expected no verification email, got %v", store.mailer.messages)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	_ "github.com/lib/pq"
+	"os"
+	"os/signal"
+	"server/config"
+	"server/database"
+	"server/logging"
+	"server/migrations"
+	"syscall"
+)
+
+const usage = `Usage: migrate <command> [argument]
+
+Commands:
+` + migrations.CommandUsage + `
+The database is configured by the same environment variables as the server.
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	conf, err := config.NewConfig(&config.Args{})
+	if err == nil {
+		err = conf.Validate()
+	}
+	if err != nil {
+		return err
+	}
+
+	logger, err := logging.New(&conf.LogConfig, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Connect(&conf.DatabaseConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(logging.WithLogger(context.Background(), logger), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	return migrator.Command(ctx, os.Stdout, args)
+}
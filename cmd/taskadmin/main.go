@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	_ "github.com/lib/pq"
+	"io"
+	"os"
+	"os/signal"
+	"server/audit"
+	"server/auth/passwords"
+	"server/auth/tokens"
+	"server/config"
+	"server/database"
+	"server/janitor"
+	"server/logging"
+	"server/mail"
+	"server/migrations"
+	"server/models"
+	"server/ratelimit"
+	"server/repositories"
+	"server/services"
+	"server/utils"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const usage = `Usage: taskadmin <command> [arguments]
+
+Commands:
+  user create [--verified] <email> <username>  create a user, --verified skips the email verification
+  user reset-password <id>                     set a new password and revoke all sessions of the user
+  user disable <id>                            disable the user and revoke all sessions
+  user enable <id>                             enable the disabled user
+  user revoke-tokens <id>                      revoke all sessions of the user
+  priorities list                              list the priorities of tasks
+  priorities add <priority>                    add a new priority of tasks
+  tokens purge                                 delete the expired tokens and rate limits
+  migrate <command> [argument]                 run a migration command
+
+Migration commands:
+` + migrations.CommandUsage + `
+Passwords are read from the first line of the standard input, so they don't end up in the shell history.
+Changes of users are recorded in the audit log with actor 0. The database is configured by the same
+environment variables as the server.
+`
+
+// errUsage is returned when the arguments don't match any command.
+var errUsage = errors.New("invalid arguments")
+
+// admin struct holds the services used by the commands.
+type admin struct {
+	userService  services.UserService
+	adminService services.AdminService
+	taskService  services.TaskService
+	migrator     *migrations.Migrator
+	janitor      *janitor.Janitor
+	stdin        *bufio.Reader
+	stdout       io.Writer
+}
+
+// operator is the token passed to the admin services. Its subject is empty, so the events are
+// recorded with actor 0, which is never the id of a user.
+var operator = tokens.Token{}
+
+func main() {
+	err := run(os.Args[1:])
+	if errors.Is(err, errUsage) {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+
+	conf, err := config.NewConfig(&config.Args{})
+	if err == nil {
+		err = conf.Validate()
+	}
+	if err != nil {
+		return err
+	}
+
+	logger, err := logging.New(&conf.LogConfig, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Connect(&conf.DatabaseConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	a, err := newAdmin(conf, db)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(logging.WithLogger(context.Background(), logger), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch args[0] {
+	case "user":
+		return a.user(ctx, args[1], args[2:])
+	case "priorities":
+		return a.priorities(ctx, args[1], args[2:])
+	case "tokens":
+		if args[1] != "purge" || len(args) != 2 {
+			return errUsage
+		}
+		return a.purgeTokens(ctx)
+	case "migrate":
+		return a.migrator.Command(ctx, a.stdout, args[1:])
+	default:
+		return errUsage
+	}
+}
+
+// user will run the commands that manage users.
+func (a *admin) user(ctx context.Context, command string, args []string) error {
+	if command == "create" {
+		flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		verified := flags.Bool("verified", false, "")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+			return errUsage
+		}
+		return a.createUser(ctx, flags.Arg(0), flags.Arg(1), *verified)
+	}
+
+	if len(args) != 1 {
+		return errUsage
+	}
+	userId, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid user id %q", args[0])
+	}
+
+	switch command {
+	case "reset-password":
+		password, err := a.readPassword()
+		if err != nil {
+			return err
+		}
+		err = responseError(a.userService.SetPassword(ctx, userId, password))
+		return a.done(err, "Password of user %d was reset", userId)
+	case "disable":
+		err = responseError(a.adminService.DisableUser(ctx, operator, userId))
+		return a.done(err, "User %d was disabled", userId)
+	case "enable":
+		err = responseError(a.adminService.EnableUser(ctx, operator, userId))
+		return a.done(err, "User %d was enabled", userId)
+	case "revoke-tokens":
+		err = responseError(a.adminService.LogoutUser(ctx, operator, userId))
+		return a.done(err, "Tokens of user %d were revoked", userId)
+	default:
+		return errUsage
+	}
+}
+
+// createUser will register the user like the register endpoint, so the same rules and password policy apply.
+func (a *admin) createUser(ctx context.Context, email, username string, verified bool) error {
+	password, err := a.readPassword()
+	if err != nil {
+		return err
+	}
+
+	payload := models.RegistrationsPayload{Email: email, Username: username, Password: password}
+	if err = responseError(payload.ValidatePayload()); err != nil {
+		return err
+	}
+
+	userId, errorResponse := a.userService.CreateUser(ctx, payload, verified)
+	return a.done(responseError(errorResponse), "Created user %d", userId)
+}
+
+// priorities will run the commands that manage the priorities of tasks.
+func (a *admin) priorities(ctx context.Context, command string, args []string) error {
+	switch {
+	case command == "list" && len(args) == 0:
+		priorities, errorResponse := a.taskService.GetPriorities(ctx)
+		if errorResponse != nil {
+			return responseError(errorResponse)
+		}
+		for _, priority := range priorities {
+			fmt.Fprintln(a.stdout, priority)
+		}
+		return nil
+	case command == "add" && len(args) == 1:
+		payload := models.PriorityPayload{Priority: args[0]}
+		err := responseError(payload.ValidatePayload())
+		if err == nil {
+			err = responseError(a.taskService.AddPriority(ctx, payload))
+		}
+		return a.done(err, "Added priority %s", payload.Priority)
+	default:
+		return errUsage
+	}
+}
+
+// purgeTokens will delete the expired tokens and rate limits in batches like the janitor of the server.
+// Nothing is deleted while a server replica holds the janitor lock.
+func (a *admin) purgeTokens(ctx context.Context) error {
+	count, err := a.janitor.RunOnce(ctx)
+	if errors.Is(err, janitor.ErrSkipped) {
+		return a.done(nil, "Skipped, a server replica is deleting the expired tokens and rate limits")
+	}
+	return a.done(err, "Deleted %d expired tokens and rate limits", count)
+}
+
+// readPassword will read the password from the first line of the standard input.
+func (a *admin) readPassword() (string, error) {
+	line, err := a.stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password is required on the standard input")
+	}
+	return password, nil
+}
+
+// done will print the message if the command succeeded.
+func (a *admin) done(err error, format string, args ...any) error {
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(a.stdout, format+"\n", args...)
+	return err
+}
+
+// responseError will convert the error response of a service to an error with all its problems.
+func responseError(errorResponse *utils.ErrorResponse) error {
+	if errorResponse == nil {
+		return nil
+	}
+
+	problems := errorResponse.Problems()
+	if len(problems) == 0 {
+		return errors.New(errorResponse.Message)
+	}
+	return fmt.Errorf("%s: %s", errorResponse.Message, strings.Join(problems, "; "))
+}
+
+// newAdmin will create the services the same way as the server does.
+func newAdmin(conf *config.Config, db *sql.DB) (*admin, error) {
+	mailer, err := mail.NewMailer(&conf.MailConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, err := passwords.NewHasherFromConfig(&conf.PasswordConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var breachedChecker passwords.BreachedChecker
+	if conf.PasswordPolicyConfig.BreachedPasswordsPath != "" {
+		breachedChecker = passwords.NewRangeChecker(conf.PasswordPolicyConfig.BreachedPasswordsPath)
+	}
+
+	migrator, err := migrations.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+
+	userRepository := repositories.NewPostgresUserRepository(db)
+	tokenRepository := repositories.NewPostgresTokenRepository(db)
+	taskRepository := repositories.NewPostgresTaskRepository(db)
+	auditRepository := repositories.NewPostgresAuditRepository(db)
+	recorder := audit.NewRepositoryRecorder(auditRepository)
+	limiter := ratelimit.NewPostgresLimiter(db, ratelimit.NewPolicy(&conf.RateLimitConfig))
+
+	return &admin{
+		userService: services.NewDefaultUserService(
+			userRepository,
+			tokenRepository,
+			repositories.NewPostgresPasswordResetRepository(db),
+			repositories.NewPostgresEmailVerificationRepository(db),
+			repositories.NewPostgresIdentityRepository(db),
+			taskRepository,
+			auditRepository,
+			recorder,
+			mailer,
+			tokens.NewJWTAuthenticator(&conf.AuthConfig),
+			hasher,
+			passwords.NewPolicy(&conf.PasswordPolicyConfig, breachedChecker),
+			limiter,
+			conf.AuthConfig.UnverifiedPolicy,
+			conf.AccountConfig.DeletionGracePeriod,
+		),
+		adminService: services.NewDefaultAdminService(
+			userRepository,
+			tokenRepository,
+			taskRepository,
+			auditRepository,
+			recorder,
+		),
+		taskService: services.NewDefaultTaskService(taskRepository),
+		migrator:    migrator,
+		janitor: janitor.NewJanitor(
+			tokenRepository,
+			limiter,
+			janitor.NewPostgresLocker(db, janitor.LockKey),
+			conf.JanitorConfig.Interval,
+			conf.JanitorConfig.BatchSize,
+		),
+		stdin:  bufio.NewReader(os.Stdin),
+		stdout: os.Stdout,
+	}, nil
+}
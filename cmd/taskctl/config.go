@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"server/models"
+)
+
+// defaultServer is the server used before the first login with --server.
+const defaultServer = "http://localhost:8080"
+
+// settings struct holds the state of taskctl stored in the config file.
+type settings struct {
+	// Server is the address of the task server.
+	Server string `json:"server"`
+	// Tokens are the tokens of the logged-in user. They are replaced on every refresh.
+	Tokens *models.TokenGroup `json:"tokens,omitempty"`
+}
+
+// configPath will return the path of the config file. TASKCTL_CONFIG overrides the default
+// taskctl/config.json in the config dir of the user.
+func configPath() (string, error) {
+	if path := os.Getenv("TASKCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "taskctl", "config.json"), nil
+}
+
+// loadSettings will read the config file. A missing file means that the user hasn't logged in yet.
+func loadSettings(path string) (*settings, error) {
+	result := &settings{Server: defaultServer}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(content, result); err != nil {
+		return nil, errors.New("invalid config file " + path + ": " + err.Error())
+	}
+	return result, nil
+}
+
+// save will write the config file readable only by the user, as it holds the tokens. The file is
+// replaced by a rename, so a crash doesn't leave a half written file.
+func (s *settings) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = file.Write(append(content, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHour is the time of dates given only by the day, for example "tomorrow".
+const defaultHour = 9
+
+// clockPattern matches times like 9am, 9:30pm and 17:00.
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+
+// weekdays are the names of the days by their lower case name and abbreviation.
+var weekdays = map[string]time.Weekday{}
+
+func init() {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		weekdays[name] = day
+		weekdays[name[:3]] = day
+	}
+}
+
+// parseDate will parse the date of a task in the local time relative to now. Besides RFC 3339 and
+// 2006-01-02 [15:04] it understands a day like today, tonight, tomorrow, friday, next week or in 3 days,
+// and a time like 9am, 9:30pm, 17:00, noon or midnight, in any order, for example "tomorrow 9am".
+// Days without a time are at 9:00. Relative times like "in 2 hours" are exact.
+func parseDate(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+
+	words := strings.Fields(strings.ToLower(value))
+	if len(words) == 0 {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+	if len(words) == 1 && words[0] == "now" {
+		return now, nil
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	hour, minute := -1, 0
+	defaultTime := defaultHour
+	var relative time.Duration
+
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		// The meridiem can be a separate word, as in "9 am".
+		if i+1 < len(words) && (words[i+1] == "am" || words[i+1] == "pm") {
+			word += words[i+1]
+			i++
+		}
+
+		switch {
+		case word == "at" || word == "on":
+		case word == "today":
+		case word == "tonight":
+			defaultTime = 20
+		case word == "tomorrow":
+			day = day.AddDate(0, 0, 1)
+		case word == "yesterday":
+			day = day.AddDate(0, 0, -1)
+		case word == "noon":
+			hour, minute = 12, 0
+		case word == "midnight":
+			hour, minute = 0, 0
+		case word == "next" && i+1 < len(words) && words[i+1] == "week":
+			day = day.AddDate(0, 0, 7)
+			i++
+		case word == "next":
+			// "next friday" is the same as "friday", the next one after today.
+		case word == "in":
+			if i+2 >= len(words) {
+				return time.Time{}, fmt.Errorf("expected an amount and a unit after in, for example in 3 days")
+			}
+			amount, err := strconv.Atoi(words[i+1])
+			if err != nil || amount < 0 {
+				return time.Time{}, fmt.Errorf("invalid amount %q", words[i+1])
+			}
+
+			switch strings.TrimSuffix(words[i+2], "s") {
+			case "minute", "min":
+				relative += time.Duration(amount) * time.Minute
+			case "hour":
+				relative += time.Duration(amount) * time.Hour
+			case "day":
+				day = day.AddDate(0, 0, amount)
+			case "week":
+				day = day.AddDate(0, 0, amount*7)
+			default:
+				return time.Time{}, fmt.Errorf("unknown unit %q", words[i+2])
+			}
+			i += 2
+		default:
+			if weekday, ok := weekdays[word]; ok {
+				days := (int(weekday)-int(day.Weekday())+6)%7 + 1
+				day = day.AddDate(0, 0, days)
+			} else if date, err := time.ParseInLocation("2006-01-02", word, now.Location()); err == nil {
+				day = date
+			} else if h, m, ok := parseClock(word); ok {
+				hour, minute = h, m
+			} else {
+				return time.Time{}, fmt.Errorf("can't understand %q in date %q", word, value)
+			}
+		}
+	}
+
+	if relative != 0 {
+		if hour >= 0 || !day.Equal(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())) {
+			return time.Time{}, fmt.Errorf("relative time %q can't be combined with a day or time", value)
+		}
+		return now.Add(relative), nil
+	}
+
+	if hour < 0 {
+		hour = defaultTime
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, now.Location()), nil
+}
+
+// parseClock will parse a time like 9am, 9:30pm or 17:00. A bare number without minutes or meridiem
+// is refused, as it could be meant as a day.
+func parseClock(value string) (int, int, bool) {
+	match := clockPattern.FindStringSubmatch(value)
+	if match == nil || (match[2] == "" && match[3] == "") {
+		return 0, 0, false
+	}
+
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	if minute > 59 {
+		return 0, 0, false
+	}
+
+	switch match[3] {
+	case "":
+		if hour > 23 {
+			return 0, 0, false
+		}
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if match[3] == "pm" {
+			hour += 12
+		}
+	}
+
+	return hour, minute, true
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	// Monday afternoon.
+	now := time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"now", now},
+		{"today", at(10, 19, 9, 0)},
+		{"tomorrow 9am", at(10, 20, 9, 0)},
+		{"Tomorrow at 9 AM", at(10, 20, 9, 0)},
+		{"noon tomorrow", at(10, 20, 12, 0)},
+		{"today 5pm", at(10, 19, 17, 0)},
+		{"9:30pm", at(10, 19, 21, 30)},
+		{"12am", at(10, 19, 0, 0)},
+		{"12pm", at(10, 19, 12, 0)},
+		{"17:45", at(10, 19, 17, 45)},
+		{"tonight", at(10, 19, 20, 0)},
+		{"friday", at(10, 23, 9, 0)},
+		{"fri 8am", at(10, 23, 8, 0)},
+		{"monday", at(10, 26, 9, 0)},
+		{"next monday", at(10, 26, 9, 0)},
+		{"next week", at(10, 26, 9, 0)},
+		{"in 3 days", at(10, 22, 9, 0)},
+		{"in 1 week 10am", at(10, 26, 10, 0)},
+		{"in 2 hours", at(10, 19, 16, 30)},
+		{"in 45 minutes", at(10, 19, 15, 15)},
+		{"2026-12-24", at(12, 24, 9, 0)},
+		{"2026-12-24 18:00", at(12, 24, 18, 0)},
+		{"2026-12-24T18:00:00+02:00", time.Date(2026, 12, 24, 16, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		got, err := parseDate(test.value, now)
+		if err != nil {
+			t.Errorf("parseDate(%q) returned error: %v", test.value, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("parseDate(%q) = %v, expected %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestParseDateErrors(t *testing.T) {
+	now := time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC)
+
+	for _, value := range []string{"", "someday", "25:00", "13pm", "0am", "9", "in x days", "in 3", "in 3 years", "tomorrow in 2 hours"} {
+		if got, err := parseDate(value, now); err == nil {
+			t.Errorf("parseDate(%q) = %v, expected error", value, got)
+		}
+	}
+}
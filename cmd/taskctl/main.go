@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"server/client"
+	"server/models"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const usage = `Usage: taskctl <command> [flags] [arguments]
+
+Commands:
+  login [--server url] <email or username>  log in, the password is read from the standard input
+  ls [--all] [--priority priority]          list the tasks that are not done, --all lists all
+  add [--description text] [--priority priority] [--date date] <name>
+                                            add a task, by default with Medium priority for today
+  done <id>...                              mark the tasks as done
+  edit [--name name] [--description text] [--priority priority] [--date date] [--done=false] <id>
+                                            change the task
+  rm <id>...                                delete the tasks
+  export [--format json|csv] [--file path]  write all tasks with their descriptions
+
+Every command accepts --output table|json|plain (-o). Ids can be shortened to any unique prefix.
+Dates are RFC 3339, 2006-01-02 [15:04] or words like "tomorrow 9am", "friday", "next week" or "in 3 days".
+
+The server and the tokens are stored in taskctl/config.json in the config dir of the user,
+TASKCTL_CONFIG changes the file and TASKCTL_SERVER the server.
+`
+
+// errUsage is returned when the arguments don't match any command.
+var errUsage = errors.New("invalid arguments")
+
+// cli struct holds the state shared by the commands.
+type cli struct {
+	client     *client.Client
+	settings   *settings
+	configPath string
+	format     outputFormat
+	stdin      *bufio.Reader
+	stdout     io.Writer
+	now        func() time.Time
+}
+
+func main() {
+	err := run(os.Args[1:])
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage):
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	case errors.Is(err, client.ErrNotLoggedIn), errors.Is(err, client.ErrInvalidToken):
+		fmt.Fprintln(os.Stderr, "Error: not logged in or the session expired, run taskctl login")
+		os.Exit(1)
+	default:
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	loaded, err := loadSettings(path)
+	if err != nil {
+		return err
+	}
+
+	c := &cli{
+		settings:   loaded,
+		configPath: path,
+		format:     tableOutput,
+		stdin:      bufio.NewReader(os.Stdin),
+		stdout:     os.Stdout,
+		now:        time.Now,
+	}
+	if server := os.Getenv("TASKCTL_SERVER"); server != "" {
+		c.settings.Server = server
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	commands := map[string]func(context.Context, []string) error{
+		"login":  c.login,
+		"ls":     c.list,
+		"add":    c.add,
+		"done":   c.done,
+		"edit":   c.edit,
+		"rm":     c.remove,
+		"export": c.export,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return errUsage
+	}
+	return command(ctx, args[1:])
+}
+
+// newClient will create the client of the configured server. The tokens are saved whenever they are refreshed.
+func (c *cli) newClient() {
+	c.client = client.NewClient(&client.Config{
+		BaseURL:    c.settings.Server,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Tokens:     c.settings.Tokens,
+		OnTokens: func(tokens models.TokenGroup) {
+			c.settings.Tokens = &tokens
+			if err := c.settings.save(c.configPath); err != nil {
+				fmt.Fprintln(os.Stderr, "Warning: the tokens were not saved:", err)
+			}
+		},
+	})
+}
+
+// flagSet will create the flags of the command together with the output flags shared by all commands.
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	output := func(value string) error {
+		format := outputFormat(value)
+		if !slices.Contains([]outputFormat{tableOutput, jsonOutput, plainOutput}, format) {
+			return fmt.Errorf("output must be table, json or plain")
+		}
+		c.format = format
+		return nil
+	}
+	flags.Func("output", "", output)
+	flags.Func("o", "", output)
+	return flags
+}
+
+// parseFlags will parse the flags anywhere between the arguments, so "add Buy milk --priority High"
+// works like "add --priority High Buy milk". Arguments after -- are never parsed as flags.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+
+		if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+			return nil, errUsage
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %w", errUsage, err)
+		}
+		rest := flags.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			break
+		}
+
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	return positional, nil
+}
+
+func (c *cli) login(ctx context.Context, args []string) error {
+	flags := c.flagSet("login")
+	server := flags.String("server", c.settings.Server, "")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	password, err := c.readPassword()
+	if err != nil {
+		return err
+	}
+
+	c.settings.Server = strings.TrimSuffix(*server, "/")
+	c.settings.Tokens = nil
+	c.newClient()
+	if _, err = c.client.Login(ctx, models.LoginPayload{Identifier: args[0], Password: password}); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.stdout, "Logged in to %s as %s\n", c.settings.Server, args[0])
+	return err
+}
+
+func (c *cli) list(ctx context.Context, args []string) error {
+	flags := c.flagSet("ls")
+	all := flags.Bool("all", false, "")
+	priority := flags.String("priority", "", "")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	tasks, err := c.tasks(ctx)
+	if err != nil {
+		return err
+	}
+
+	tasks = slices.DeleteFunc(tasks, func(task models.TaskPayload) bool {
+		return (task.Done && !*all) || (*priority != "" && !strings.EqualFold(task.Priority, *priority))
+	})
+	return printTasks(c.stdout, c.format, tasks)
+}
+
+func (c *cli) add(ctx context.Context, args []string) error {
+	flags := c.flagSet("add")
+	description := flags.String("description", "", "")
+	priority := flags.String("priority", "Medium", "")
+	date := flags.String("date", "today", "")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	payload := models.NewTaskPayload{
+		Name:        strings.Join(args, " "),
+		Description: *description,
+		Priority:    *priority,
+	}
+	// The server requires a description, which is often the same as the name for short tasks.
+	if payload.Description == "" {
+		payload.Description = payload.Name
+	}
+	if payload.Date.Time, err = parseDate(*date, c.now()); err != nil {
+		return err
+	}
+
+	c.newClient()
+	task, err := c.client.AddTask(ctx, payload)
+	if err != nil {
+		return err
+	}
+	return printTasks(c.stdout, c.format, []models.TaskPayload{*task})
+}
+
+func (c *cli) done(ctx context.Context, args []string) error {
+	args, err := parseFlags(c.flagSet("done"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	tasks, err := c.resolve(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		tasks[i].Done = true
+		if err = c.client.UpdateTask(ctx, tasks[i]); err != nil {
+			return err
+		}
+	}
+	return printTasks(c.stdout, c.format, tasks)
+}
+
+func (c *cli) edit(ctx context.Context, args []string) error {
+	flags := c.flagSet("edit")
+	name := flags.String("name", "", "")
+	description := flags.String("description", "", "")
+	priority := flags.String("priority", "", "")
+	date := flags.String("date", "", "")
+	done := flags.Bool("done", false, "")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	tasks, err := c.resolve(ctx, args)
+	if err != nil {
+		return err
+	}
+	task := tasks[0]
+
+	// Only the given flags change the task, so --done=false can be told apart from a missing flag.
+	var changed bool
+	var dateErr error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			task.Name, changed = *name, true
+		case "description":
+			task.Description, changed = *description, true
+		case "priority":
+			task.Priority, changed = *priority, true
+		case "date":
+			task.Date.Time, dateErr = parseDate(*date, c.now())
+			changed = true
+		case "done":
+			task.Done, changed = *done, true
+		}
+	})
+	if dateErr != nil {
+		return dateErr
+	}
+	if !changed {
+		return errors.New("nothing to change, use --name, --description, --priority, --date or --done")
+	}
+
+	if err = c.client.UpdateTask(ctx, task); err != nil {
+		return err
+	}
+	return printTasks(c.stdout, c.format, []models.TaskPayload{task})
+}
+
+func (c *cli) remove(ctx context.Context, args []string) error {
+	args, err := parseFlags(c.flagSet("rm"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	tasks, err := c.resolve(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if err = c.client.DeleteTask(ctx, task.Id); err != nil {
+			return err
+		}
+	}
+	return printTasks(c.stdout, c.format, tasks)
+}
+
+func (c *cli) export(ctx context.Context, args []string) error {
+	flags := c.flagSet("export")
+	format := flags.String("format", "json", "")
+	file := flags.String("file", "", "")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 || (*format != "json" && *format != "csv") {
+		return errUsage
+	}
+
+	tasks, err := c.tasks(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return exportTasks(c.stdout, *format, tasks)
+	}
+
+	f, err := os.OpenFile(*file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err = exportTasks(f, *format, tasks); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// tasks will return all tasks of the user ordered by date and name.
+func (c *cli) tasks(ctx context.Context) ([]models.TaskPayload, error) {
+	c.newClient()
+	tasks, err := c.client.GetTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(tasks, func(a, b models.TaskPayload) int {
+		if result := a.Date.Compare(b.Date.Time); result != 0 {
+			return result
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return tasks, nil
+}
+
+// resolve will find the tasks by their ids or unique prefixes of the ids.
+func (c *cli) resolve(ctx context.Context, ids []string) ([]models.TaskPayload, error) {
+	tasks, err := c.tasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.TaskPayload, 0, len(ids))
+	for _, id := range ids {
+		var matches []models.TaskPayload
+		for _, task := range tasks {
+			if strings.HasPrefix(task.Id.String(), strings.ToLower(id)) {
+				matches = append(matches, task)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("no task with id %s", id)
+		case 1:
+			result = append(result, matches[0])
+		default:
+			return nil, fmt.Errorf("id %s matches %d tasks, use a longer prefix", id, len(matches))
+		}
+	}
+	return result, nil
+}
+
+// readPassword will read the password from the first line of the standard input. On a terminal
+// the password is prompted for and not echoed, if stty is available.
+func (c *cli) readPassword() (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+		if stty("-echo") == nil {
+			defer func() {
+				_ = stty("echo")
+				fmt.Fprintln(os.Stderr)
+			}()
+		}
+	}
+
+	line, err := c.stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password is required")
+	}
+	return password, nil
+}
+
+// stty will change the mode of the terminal of the standard input.
+func stty(mode string) error {
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"server/models"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// outputFormat is the format tasks are printed in.
+type outputFormat string
+
+const (
+	// tableOutput is an aligned table for people.
+	tableOutput outputFormat = "table"
+	// jsonOutput is the tasks as returned by the api.
+	jsonOutput outputFormat = "json"
+	// plainOutput is a task per line with tab separated fields and no header, for scripts.
+	plainOutput outputFormat = "plain"
+)
+
+// printTasks will write the tasks in the format.
+func printTasks(w io.Writer, format outputFormat, tasks []models.TaskPayload) error {
+	switch format {
+	case jsonOutput:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(tasks)
+	case plainOutput:
+		for _, task := range tasks {
+			_, err := fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\n",
+				task.Id, task.Done, task.Date.Format(time.RFC3339), task.Priority, task.Name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "ID\tDONE\tDATE\tPRIORITY\tNAME")
+		for _, task := range tasks {
+			done := ""
+			if task.Done {
+				done = "x"
+			}
+			// The prefix is enough to refer to the task in the other commands.
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n",
+				task.Id.String()[:8], done, task.Date.Local().Format("Mon 2006-01-02 15:04"), task.Priority, task.Name)
+		}
+		return writer.Flush()
+	}
+}
+
+// exportTasks will write the tasks with all fields in the json or csv format.
+func exportTasks(w io.Writer, format string, tasks []models.TaskPayload) error {
+	if format == "csv" {
+		return exportCSV(w, tasks)
+	}
+	return printTasks(w, jsonOutput, tasks)
+}
+
+// exportCSV will write the tasks with all fields as CSV with a header.
+func exportCSV(w io.Writer, tasks []models.TaskPayload) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "name", "description", "priority", "date", "done"}); err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		err := writer.Write([]string{
+			task.Id.String(),
+			task.Name,
+			task.Description,
+			task.Priority,
+			task.Date.Format(time.RFC3339),
+			strconv.FormatBool(task.Done),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
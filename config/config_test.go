@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewConfigPrecedence(t *testing.T) {
+	file := writeFile(t, "server_addr: ':7000'\njwt_issuer: file\nshutdown_timeout: 10s\noidc_scopes: [openid, email]\n")
+	t.Setenv("JWT_ISSUER", "env")
+	t.Setenv("SHUTDOWN_DELAY", "5s")
+	t.Setenv("SHUTDOWN_TIMEOUT", "20s")
+
+	args, err := ParseArgs([]string{"--config", file, "--shutdown-timeout=40s", "--migrate-on-start"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := NewConfig(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.ServerAddr != ":7000" {
+		t.Fatalf("Expected the address of the file, got %q", conf.ServerAddr)
+	}
+	if conf.AuthConfig.JwtIssuer != "env" || conf.ShutdownDelay != time.Second*5 {
+		t.Fatalf("Expected the env to override the file, got %q and %v", conf.AuthConfig.JwtIssuer, conf.ShutdownDelay)
+	}
+	if conf.ShutdownTimeout != time.Second*40 || !conf.MigrateOnStart {
+		t.Fatalf("Expected the flags to override the env, got %v and %v", conf.ShutdownTimeout, conf.MigrateOnStart)
+	}
+	if strings.Join(conf.OIDCConfig.Scopes, " ") != "openid email" {
+		t.Fatalf("Expected the scopes of the file, got %v", conf.OIDCConfig.Scopes)
+	}
+	if conf.RateLimitConfig.MaxAttempts != 10 {
+		t.Fatalf("Expected the default, got %d", conf.RateLimitConfig.MaxAttempts)
+	}
+}
+
+func TestParseArgsFlagsWithoutValue(t *testing.T) {
+	args, err := ParseArgs([]string{"--database-url", "--server-addr", ":9000", "--migrate-on-start"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewConfig(args)
+	if err == nil || !strings.Contains(err.Error(), "flag --database-url requires a value") {
+		t.Fatalf("Expected the flag without a value to be refused, got %v", err)
+	}
+	if strings.Contains(err.Error(), "migrate-on-start") {
+		t.Fatalf("Expected the boolean flag without a value to be allowed, got %v", err)
+	}
+}
+
+func TestNewConfigSecretFile(t *testing.T) {
+	t.Setenv("JWT_SECRET_FILE", writeFile(t, "secret-from-file\n"))
+
+	conf, err := NewConfig(&Args{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(conf.AuthConfig.JwtSecret) != "secret-from-file" {
+		t.Fatalf("Expected the secret of the file without the new line, got %q", conf.AuthConfig.JwtSecret)
+	}
+
+	t.Setenv("JWT_SECRET", "secret")
+	if _, err = NewConfig(&Args{}); err == nil {
+		t.Fatal("Expected error when both the variable and the file are set")
+	}
+}
+
+func TestNewConfigInvalidValues(t *testing.T) {
+	t.Setenv("MAX_OPEN_CONNECTIONS", "ten")
+	t.Setenv("ARGON2_PARALLELISM", "300")
+
+	_, err := NewConfig(&Args{Values: map[string]string{"SERVER_ADRR": ":80"}})
+	if err == nil {
+		t.Fatal("Expected invalid values to be refused")
+	}
+	for _, expected := range []string{"MAX_OPEN_CONNECTIONS", "ARGON2_PARALLELISM", "--server-adrr"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Expected error of %s, got %v", expected, err)
+		}
+	}
+}
+
+func TestValidateProduction(t *testing.T) {
+	conf, err := NewConfig(&Args{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.Validate(); err != nil {
+		t.Fatalf("Expected the defaults to be valid in development, got %v", err)
+	}
+
+	conf.Environment = Production
+	err = conf.Validate()
+	if err == nil || !strings.Contains(err.Error(), "JWT_SECRET") || !strings.Contains(err.Error(), "DATABASE_URL") {
+		t.Fatalf("Expected the default secrets to be refused in production, got %v", err)
+	}
+
+	conf.AuthConfig.JwtSecret = []byte(strings.Repeat("s", minJwtSecretLength))
+	conf.DatabaseConfig.Url = "postgres://app:password@db/app"
+	if err = conf.Validate(); err != nil {
+		t.Fatalf("Expected the configured secrets to be valid, got %v", err)
+	}
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	conf, err := NewConfig(&Args{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.ProxyHeader = "X-Real-IP"
+	if err = conf.Validate(); err == nil || !strings.Contains(err.Error(), "TRUSTED_PROXIES") {
+		t.Fatalf("Expected the proxy header to require trusted proxies, got %v", err)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1, 192.168.0.0/16")
+	if conf, err = NewConfig(&Args{Values: map[string]string{"PROXY_HEADER": "X-Real-IP"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.Validate(); err != nil || len(conf.TrustedProxies) != 2 {
+		t.Fatalf("Expected the trusted proxies to be valid, got %v and %v", conf.TrustedProxies, err)
+	}
+
+	conf.TrustedProxies = append(conf.TrustedProxies, "proxy.local")
+	if err = conf.Validate(); err == nil || !strings.Contains(err.Error(), "proxy.local") {
+		t.Fatalf("Expected the host name to be refused, got %v", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	t.Setenv("JWT_SECRET", "jwt-secret-value")
+	t.Setenv("DATABASE_URL", "postgres://app:db-password@db/app")
+
+	conf, err := NewConfig(&Args{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	if err = conf.Print(&output); err != nil {
+		t.Fatal(err)
+	}
+
+	printed := output.String()
+	if strings.Contains(printed, "jwt-secret-value") || strings.Contains(printed, "db-password") {
+		t.Fatalf("Expected the secrets to be redacted, got\n%s", printed)
+	}
+	if !strings.Contains(printed, "database_url: postgres://app:xxxxx@db/app # env") {
+		t.Fatalf("Expected the database url with redacted password, got\n%s", printed)
+	}
+	if !strings.Contains(printed, "jwt_issuer: com.localhost # default") {
+		t.Fatalf("Expected the settings with their source, got\n%s", printed)
+	}
+}
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source is the layer a setting was read from.
+type Source string
+
+const (
+	DefaultSource Source = "default"
+	FileSource    Source = "file"
+	EnvSource     Source = "env"
+	FlagSource    Source = "flag"
+)
+
+// Args struct holds the parsed command line arguments.
+type Args struct {
+	// ConfigFile is the YAML file with the settings. It can be set also by CONFIG_FILE.
+	ConfigFile string
+	// PrintConfig will print the resolved configuration with redacted secrets instead of starting the server.
+	PrintConfig bool
+	// Values are the settings set by flags by their key.
+	Values map[string]string
+	// bare are the keys of the flags given without a value. Only boolean settings can be set so.
+	bare map[string]bool
+}
+
+// ParseArgs will parse the command line arguments. Every setting can be set by a flag named after its
+// environment variable in lower case with dashes, for example --jwt-issuer=issuer or --jwt-issuer issuer.
+// Boolean settings can be set without a value, other settings without a value are refused by [NewConfig].
+func ParseArgs(args []string) (*Args, error) {
+	result := &Args{Values: map[string]string{}, bare: map[string]bool{}}
+	for i := 0; i < len(args); i++ {
+		name, found := strings.CutPrefix(args[i], "--")
+		if !found {
+			return nil, fmt.Errorf("unexpected argument %q", args[i])
+		}
+
+		name, value, hasValue := strings.Cut(name, "=")
+		if !hasValue {
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+				value, hasValue = args[i+1], true
+				i++
+			} else {
+				value = "true"
+			}
+		}
+
+		switch name {
+		case "config":
+			if !hasValue {
+				return nil, errors.New("flag --config requires a file")
+			}
+			result.ConfigFile = value
+		case "print-config":
+			printConfig, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("flag --print-config: invalid bool %q", value)
+			}
+			result.PrintConfig = printConfig
+		default:
+			key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+			result.Values[key] = value
+			if !hasValue {
+				result.bare[key] = true
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// setting struct holds the resolved value of a single setting.
+type setting struct {
+	key    string
+	value  string
+	source Source
+	secret bool
+}
+
+// loader resolves the settings from the layers. The settings are identified by their environment
+// variable, which is used as the key in the file and, in lower case with dashes, as the flag.
+type loader struct {
+	file  map[string]string
+	flags map[string]string
+	// bare are the keys of the flags given without a value.
+	bare map[string]bool
+	// booleans are the keys of the boolean settings, the only ones that can be set by a flag without a value.
+	booleans map[string]bool
+	// settings are the resolved settings in the order they were loaded.
+	settings []setting
+	errors   []error
+}
+
+// lookup will return the value of the setting from the layer with the highest precedence.
+// The value of environment variable KEY_FILE is a path of a file holding the value, as used by Docker secrets.
+func (l *loader) lookup(key string) (string, Source, error) {
+	if value, ok := l.flags[key]; ok {
+		return value, FlagSource, nil
+	}
+
+	value, inEnv := os.LookupEnv(key)
+	path, inFile := os.LookupEnv(key + "_FILE")
+	if inEnv && inFile {
+		return "", "", fmt.Errorf("%s and %s_FILE can't be set together", key, key)
+	} else if inEnv {
+		return value, EnvSource, nil
+	} else if inFile {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("%s_FILE: %w", key, err)
+		}
+		// Files usually end with a new line that is not part of the value.
+		return strings.TrimRight(string(content), "\r\n"), EnvSource, nil
+	}
+
+	if value, ok := l.file[key]; ok {
+		return value, FileSource, nil
+	}
+
+	return "", DefaultSource, nil
+}
+
+// load will resolve the setting and parse it. Parse errors are collected and returned by [NewConfig].
+func load[T any](l *loader, key string, fallback T, secret bool, format func(T) string, parse func(string) (T, error)) T {
+	value, source, err := l.lookup(key)
+	if err != nil {
+		l.errors = append(l.errors, err)
+		return fallback
+	}
+
+	result := fallback
+	if source == DefaultSource {
+		value = format(fallback)
+	} else if result, err = parse(value); err != nil {
+		l.errors = append(l.errors, fmt.Errorf("%s: invalid value %q from %s", key, value, source))
+		return fallback
+	}
+
+	l.settings = append(l.settings, setting{key: key, value: value, source: source, secret: secret})
+	return result
+}
+
+// parseString is the parse function of string settings.
+func parseString(value string) (string, error) {
+	return value, nil
+}
+
+func (l *loader) string(key, fallback string) string {
+	return load(l, key, fallback, false, func(v string) string { return v }, parseString)
+}
+
+// secret will resolve the setting like [loader.string], but its value is redacted when printed.
+func (l *loader) secret(key, fallback string) string {
+	return load(l, key, fallback, true, func(v string) string { return v }, parseString)
+}
+
+func (l *loader) int(key string, fallback int) int {
+	return load(l, key, fallback, false, strconv.Itoa, strconv.Atoi)
+}
+
+// uint will resolve the setting as unsigned integer that fits into the bits.
+func (l *loader) uint(key string, fallback uint64, bits int) uint64 {
+	return load(l, key, fallback, false, func(v uint64) string {
+		return strconv.FormatUint(v, 10)
+	}, func(value string) (uint64, error) {
+		return strconv.ParseUint(value, 10, bits)
+	})
+}
+
+func (l *loader) float(key string, fallback float64) float64 {
+	return load(l, key, fallback, false, func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}, func(value string) (float64, error) {
+		return strconv.ParseFloat(value, 64)
+	})
+}
+
+func (l *loader) duration(key string, fallback time.Duration) time.Duration {
+	return load(l, key, fallback, false, time.Duration.String, time.ParseDuration)
+}
+
+func (l *loader) bool(key string, fallback bool) bool {
+	l.booleans[key] = true
+	return load(l, key, fallback, false, strconv.FormatBool, strconv.ParseBool)
+}
+
+// unknown will return error for every setting of the file or the flags that doesn't exist,
+// so typos don't silently leave the default.
+func (l *loader) unknown() []error {
+	known := map[string]bool{}
+	for _, s := range l.settings {
+		known[s.key] = true
+	}
+
+	var errs []error
+	for key := range l.file {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("unknown setting %s in the config file", strings.ToLower(key)))
+		}
+	}
+	for key := range l.flags {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("unknown flag --%s", strings.ToLower(strings.ReplaceAll(key, "_", "-"))))
+		}
+	}
+	return errs
+}
+
+// missingValues will return error for every flag given without a value that is not a boolean setting,
+// so --database-url --port 8080 doesn't silently set the url to true.
+func (l *loader) missingValues() []error {
+	known := map[string]bool{}
+	for _, s := range l.settings {
+		known[s.key] = true
+	}
+
+	var errs []error
+	for key := range l.bare {
+		if known[key] && !l.booleans[key] {
+			errs = append(errs, fmt.Errorf("flag --%s requires a value", strings.ToLower(strings.ReplaceAll(key, "_", "-"))))
+		}
+	}
+	return errs
+}
+
+// readFile will read the flat YAML file with the settings by their lower case key,
+// for example jwt_issuer: issuer. Lists are joined by spaces.
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]any
+	if err = yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	for key, value := range document {
+		switch value := value.(type) {
+		case map[string]any:
+			return nil, fmt.Errorf("config file %s: setting %s must not be nested", path, key)
+		case []any:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[strings.ToUpper(key)] = strings.Join(items, " ")
+		case nil:
+			values[strings.ToUpper(key)] = ""
+		default:
+			values[strings.ToUpper(key)] = fmt.Sprint(value)
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces the values of secrets when the configuration is printed.
+const redacted = "[REDACTED]"
+
+// Print will write the resolved settings in the format of the config file, with the source
+// of every setting as a comment. Secrets are redacted, so the output can be shared.
+func (c *Config) Print(w io.Writer) error {
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings {
+		value := s.value
+		if s.secret {
+			value = redact(value)
+		}
+
+		document.Content = append(document.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: strings.ToLower(s.key)},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: value, LineComment: string(s.source)},
+		)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// redact will hide the secret. Only the password of urls is hidden, so the host of the database stays visible.
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	if parsed, err := url.Parse(value); err == nil && parsed.Scheme != "" && parsed.User != nil {
+		if _, hasPassword := parsed.User.Password(); hasPassword {
+			return parsed.Redacted()
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// minJwtSecretLength is the minimal length of the JWT secret in production. HS256 keys should have at least 256 bits.
+const minJwtSecretLength = 32
+
+// Validate will check the settings and return all problems together. In [Production] the default
+// secrets are refused.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(key string, value string, allowed ...string) {
+		check(slices.Contains(allowed, value), "%s must be one of %v, got %q", key, allowed, value)
+	}
+
+	oneOf("ENVIRONMENT", string(c.Environment), string(Development), string(Production))
+	check(c.ServerAddr != "", "SERVER_ADDR is required")
+	check(c.ProxyHeader == "" || len(c.TrustedProxies) > 0, "TRUSTED_PROXIES is required by PROXY_HEADER")
+	for _, proxy := range c.TrustedProxies {
+		_, addrErr := netip.ParseAddr(proxy)
+		_, prefixErr := netip.ParsePrefix(proxy)
+		check(addrErr == nil || prefixErr == nil, "TRUSTED_PROXIES must contain ips or CIDR ranges, got %q", proxy)
+	}
+	check(c.ShutdownDelay >= 0, "SHUTDOWN_DELAY can't be negative")
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(c.HealthCheckTimeout > 0, "HEALTH_CHECK_TIMEOUT must be positive")
+	oneOf("ERROR_FORMAT", c.ErrorFormat, "problem", "legacy")
+
+	check(c.DatabaseConfig.Url != "", "DATABASE_URL is required")
+	check(c.DatabaseConfig.MaxOpenConnections > 0, "MAX_OPEN_CONNECTIONS must be positive")
+	check(c.DatabaseConfig.MaxIdleConnections >= 0, "MAX_IDLE_CONNECTIONS can't be negative")
+
+	check(len(c.AuthConfig.JwtSecret) > 0, "JWT_SECRET is required")
+	check(c.AuthConfig.JwtIssuer != "", "JWT_ISSUER is required")
+	oneOf("UNVERIFIED_POLICY", string(c.AuthConfig.UnverifiedPolicy),
+		string(UnverifiedAllow), string(UnverifiedReadOnly), string(UnverifiedBlock))
+
+	check(c.MailConfig.From != "", "MAIL_FROM is required")
+	oneOf("MAIL_BACKEND", string(c.MailConfig.Backend), string(LogMailBackend), string(FileMailBackend))
+	check(c.MailConfig.Backend != FileMailBackend || c.MailConfig.FilePath != "", "MAIL_FILE_PATH is required by the file backend")
+
+	check(c.AccountConfig.DeletionGracePeriod >= 0, "ACCOUNT_DELETION_GRACE_PERIOD can't be negative")
+	check(c.AccountConfig.DeletionPurgeInterval > 0, "ACCOUNT_DELETION_PURGE_INTERVAL must be positive")
+
+	check(c.RateLimitConfig.FreeAttempts >= 0, "RATE_LIMIT_FREE_ATTEMPTS can't be negative")
+	check(c.RateLimitConfig.MaxAttempts > c.RateLimitConfig.FreeAttempts, "RATE_LIMIT_MAX_ATTEMPTS must be greater than RATE_LIMIT_FREE_ATTEMPTS")
+	check(c.RateLimitConfig.BaseDelay > 0, "RATE_LIMIT_BASE_DELAY must be positive")
+	check(c.RateLimitConfig.Lockout > 0, "RATE_LIMIT_LOCKOUT must be positive")
+	check(c.RateLimitConfig.Window > 0, "RATE_LIMIT_WINDOW must be positive")
+
+	oneOf("PASSWORD_ALGORITHM", string(c.PasswordConfig.Algorithm), string(Argon2idAlgorithm), string(BcryptAlgorithm))
+	check(c.PasswordConfig.Argon2Memory > 0, "ARGON2_MEMORY must be positive")
+	check(c.PasswordConfig.Argon2Iterations > 0, "ARGON2_ITERATIONS must be positive")
+	check(c.PasswordConfig.Argon2Parallelism > 0, "ARGON2_PARALLELISM must be positive")
+	check(c.PasswordConfig.Argon2SaltLength >= 8, "ARGON2_SALT_LENGTH must be at least 8")
+	check(c.PasswordConfig.Argon2KeyLength >= 16, "ARGON2_KEY_LENGTH must be at least 16")
+	check(c.PasswordConfig.BcryptCost >= 4 && c.PasswordConfig.BcryptCost <= 31, "BCRYPT_COST must be between 4 and 31")
+
+	check(c.PasswordPolicyConfig.MinLength > 0, "PASSWORD_MIN_LENGTH must be positive")
+	check(c.PasswordPolicyConfig.MaxLength >= c.PasswordPolicyConfig.MinLength, "PASSWORD_MAX_LENGTH can't be less than PASSWORD_MIN_LENGTH")
+	check(c.PasswordPolicyConfig.MaxRepeats >= 0, "PASSWORD_MAX_REPEATS can't be negative")
+
+	if c.OIDCConfig.Enabled() {
+		check(c.OIDCConfig.ClientId != "", "OIDC_CLIENT_ID is required by OIDC_ISSUER")
+		check(c.OIDCConfig.ClientSecret != "", "OIDC_CLIENT_SECRET is required by OIDC_ISSUER")
+		check(c.OIDCConfig.RedirectURL != "", "OIDC_REDIRECT_URL is required by OIDC_ISSUER")
+		check(slices.Contains(c.OIDCConfig.Scopes, "openid") && slices.Contains(c.OIDCConfig.Scopes, "email"),
+			"OIDC_SCOPES must contain openid and email")
+	}
+
+	if c.JanitorConfig.Enabled {
+		check(c.JanitorConfig.Interval > 0, "JANITOR_INTERVAL must be positive")
+		check(c.JanitorConfig.BatchSize > 0, "JANITOR_BATCH_SIZE must be positive")
+	}
+
+	oneOf("TRACING_EXPORTER", string(c.TracingConfig.Exporter),
+		string(NoneTracingExporter), string(StdoutTracingExporter), string(OTLPTracingExporter))
+	check(c.TracingConfig.ServiceName != "", "TRACING_SERVICE_NAME is required")
+	check(c.TracingConfig.SampleRatio >= 0 && c.TracingConfig.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.LogConfig.Level)) == nil, "LOG_LEVEL must be debug, info, warn or error, got %q", c.LogConfig.Level)
+	oneOf("LOG_FORMAT", strings.ToLower(c.LogConfig.Format), "json", "text")
+
+	if c.Environment == Production {
+		for _, key := range c.DefaultSecrets() {
+			errs = append(errs, fmt.Errorf("%s must be set in production", key))
+		}
+		check(len(c.AuthConfig.JwtSecret) >= minJwtSecretLength, "JWT_SECRET must have at least %d bytes in production", minJwtSecretLength)
+	}
+
+	return errors.Join(errs...)
+}
+
+// DefaultSecrets will return the keys of the secrets that use the insecure defaults.
+func (c *Config) DefaultSecrets() []string {
+	var keys []string
+	if string(c.AuthConfig.JwtSecret) == defaultJwtSecret {
+		keys = append(keys, "JWT_SECRET")
+	}
+	if c.DatabaseConfig.Url == defaultDatabaseUrl {
+		keys = append(keys, "DATABASE_URL")
+	}
+	return keys
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"server/auth/tokens"
+	"server/models"
+	"server/services"
+	"server/tracing"
+	"server/utils"
+)
+
+// AdminHandler interface handles requests for administration of users.
+type AdminHandler interface {
+	// SearchUsers handler used to list and search users.
+	SearchUsers() fiber.Handler
+	// DisableUser handler used to disable the account of a user.
+	DisableUser() fiber.Handler
+	// EnableUser handler used to enable the account of a user.
+	EnableUser() fiber.Handler
+	// LogoutUser handler used to revoke all sessions of a user.
+	LogoutUser() fiber.Handler
+	// GetTaskCounts handler used to view the number of tasks of a user.
+	GetTaskCounts() fiber.Handler
+	// SearchAuditEvents handler used to search the security events of all users.
+	SearchAuditEvents() fiber.Handler
+}
+
+// DefaultAdminHandler is the default implementation of [AdminHandler].
+type DefaultAdminHandler struct {
+	adminService services.AdminService
+}
+
+// parseUserId will parse the id param of the user.
+// If the id is invalid it responds with an error and returns false.
+func parseUserId(c *fiber.Ctx) (int, bool) {
+	userId, err := c.ParamsInt("id")
+	if err != nil || userId <= 0 {
+		utils.HandleErrorResponse(c, utils.NewErrorResponse(utils.CodeInvalidId, "Invalid user id", fiber.StatusBadRequest))
+		return 0, false
+	}
+
+	return userId, true
+}
+
+func (h *DefaultAdminHandler) SearchUsers() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "AdminHandler.SearchUsers")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		var query models.UserSearchQuery
+		if ok, err := utils.ParseQuery(c, &query); !ok {
+			return err
+		}
+
+		users, err := h.adminService.SearchUsers(c.UserContext(), query)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		return c.JSON(users)
+	}
+}
+
+func (h *DefaultAdminHandler) DisableUser() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "AdminHandler.DisableUser")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		userId, ok := parseUserId(c)
+		if !ok {
+			return nil
+		}
+
+		err := h.adminService.DisableUser(c.UserContext(), *claims, userId)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultAdminHandler) EnableUser() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "AdminHandler.EnableUser")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		userId, ok := parseUserId(c)
+		if !ok {
+			return nil
+		}
+
+		err := h.adminService.EnableUser(c.UserContext(), *claims, userId)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultAdminHandler) LogoutUser() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "AdminHandler.LogoutUser")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		userId, ok := parseUserId(c)
+		if !ok {
+			return nil
+		}
+
+		err := h.adminService.LogoutUser(c.UserContext(), *claims, userId)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultAdminHandler) GetTaskCounts() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "AdminHandler.GetTaskCounts")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		userId, ok := parseUserId(c)
+		if !ok {
+			return nil
+		}
+
+		counts, err := h.adminService.GetTaskCounts(c.UserContext(), userId)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		return c.JSON(counts)
+	}
+}
+
+func (h *DefaultAdminHandler) SearchAuditEvents() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "AdminHandler.SearchAuditEvents")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		var query models.AuditQuery
+		if ok, err := utils.ParseQuery(c, &query); !ok {
+			return err
+		}
+
+		events, err := h.adminService.SearchAuditEvents(c.UserContext(), query)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		return c.JSON(events)
+	}
+}
+
+func NewDefaultAdminHandler(adminService services.AdminService) *DefaultAdminHandler {
+	return &DefaultAdminHandler{adminService}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"server/audit"
+)
+
+// ClientInfoMiddleware will add the ip and user agent of the client to the user context,
+// so the services can record them with the security events.
+func ClientInfoMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.SetUserContext(audit.WithClient(c.UserContext(), audit.Client{
+			IP:        c.IP(),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+		}))
+
+		return c.Next()
+	}
+}
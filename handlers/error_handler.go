@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"server/httpstatus"
+	"server/utils"
+)
+
+// ErrorHandler will respond to errors returned by the handlers with [utils.ErrorResponse].
+// Payloads that can't be parsed are client errors. Server errors are logged and their details
+// are not sent to the client.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var errorResponse *utils.ErrorResponse
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) && fiberErr.Code < fiber.StatusInternalServerError {
+		errorResponse = utils.NewErrorResponse(utils.StatusCode(fiberErr.Code), fiberErr.Message, fiberErr.Code)
+	} else if httpstatus.MalformedPayload(err) {
+		errorResponse = utils.MalformedPayloadErrorResponse()
+	} else {
+		errorResponse = utils.InternalError(c.UserContext(), err)
+	}
+
+	utils.HandleErrorResponse(c, errorResponse)
+	return nil
+}
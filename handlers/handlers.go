@@ -2,6 +2,11 @@ package handlers
 
 // Handlers struct will hold all handlers.
 type Handlers struct {
-	UserHandler UserHandler
-	TaskHandler TaskHandler
+	UserHandler   UserHandler
+	TaskHandler   TaskHandler
+	AdminHandler  AdminHandler
+	OAuthHandler  OAuthHandler
+	HealthHandler HealthHandler
+	// OIDCHandler is nil if the login with an identity provider is not configured.
+	OIDCHandler OIDCHandler
 }
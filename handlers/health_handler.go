@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"server/health"
+)
+
+// HealthHandler interface handles the probes of the orchestrator.
+type HealthHandler interface {
+	// Liveness handler used to check that the process is alive.
+	Liveness() fiber.Handler
+	// Readiness handler used to check that the server and its dependencies can handle requests.
+	Readiness() fiber.Handler
+}
+
+// DefaultHealthHandler is the default implementation of [HealthHandler].
+type DefaultHealthHandler struct {
+	checker *health.Checker
+}
+
+func (h *DefaultHealthHandler) Liveness() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": health.StatusUp})
+	}
+}
+
+func (h *DefaultHealthHandler) Readiness() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		report := h.checker.Check(c.UserContext())
+		if report.Status != health.StatusUp {
+			c.Status(fiber.StatusServiceUnavailable)
+		}
+
+		return c.JSON(report)
+	}
+}
+
+func NewDefaultHealthHandler(checker *health.Checker) *DefaultHealthHandler {
+	return &DefaultHealthHandler{checker}
+}
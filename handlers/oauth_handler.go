@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"github.com/gofiber/fiber/v2"
+	"net/url"
+	"server/auth/tokens"
+	"server/models"
+	"server/services"
+	"server/tracing"
+	"server/utils"
+	"strings"
+)
+
+// OAuthHandler interface handles requests of the oauth authorization server.
+type OAuthHandler interface {
+	// RegisterClient handler used to register a new client of the authenticated user.
+	RegisterClient() fiber.Handler
+	// GetClients handler used to list the clients of the authenticated user.
+	GetClients() fiber.Handler
+	// DeleteClient handler used to delete a client of the authenticated user.
+	DeleteClient() fiber.Handler
+	// GetConsent handler used to show the consent screen of an authorization request.
+	GetConsent() fiber.Handler
+	// Authorize handler used to approve or deny an authorization request.
+	Authorize() fiber.Handler
+	// Token handler used by clients to exchange codes and refresh tokens.
+	Token() fiber.Handler
+}
+
+// DefaultOAuthHandler is the default implementation of [OAuthHandler].
+type DefaultOAuthHandler struct {
+	oauthService services.OAuthService
+}
+
+func (h *DefaultOAuthHandler) RegisterClient() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "OAuthHandler.RegisterClient")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		var payload models.RegisterClientPayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		client, err := h.oauthService.RegisterClient(c.UserContext(), *claims, payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(client)
+	}
+}
+
+func (h *DefaultOAuthHandler) GetClients() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "OAuthHandler.GetClients")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		clients, err := h.oauthService.GetClients(c.UserContext(), *claims)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		return c.JSON(clients)
+	}
+}
+
+func (h *DefaultOAuthHandler) DeleteClient() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "OAuthHandler.DeleteClient")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		err := h.oauthService.DeleteClient(c.UserContext(), *claims, c.Params("id"))
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultOAuthHandler) GetConsent() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "OAuthHandler.GetConsent")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		var query models.AuthorizeQuery
+		if ok, err := utils.ParseQuery(c, &query); !ok {
+			return err
+		}
+
+		consent, err := h.oauthService.GetConsent(c.UserContext(), *claims, query)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		return c.JSON(consent)
+	}
+}
+
+func (h *DefaultOAuthHandler) Authorize() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "OAuthHandler.Authorize")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		var payload models.AuthorizePayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		result, err := h.oauthService.Authorize(c.UserContext(), *claims, payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		return c.JSON(result)
+	}
+}
+
+func (h *DefaultOAuthHandler) Token() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "OAuthHandler.Token")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		var request models.OAuthTokenRequest
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.NewOAuthError("invalid_request", "", fiber.StatusBadRequest))
+		}
+
+		// Confidential clients may authenticate with basic auth instead of the body params.
+		if clientId, clientSecret, ok := parseBasicAuth(c.Get(fiber.HeaderAuthorization)); ok {
+			request.ClientId = clientId
+			request.ClientSecret = clientSecret
+		}
+
+		// The token response must never be cached.
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		c.Set(fiber.HeaderPragma, "no-cache")
+
+		response, err := h.oauthService.Exchange(c.UserContext(), request)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		return c.JSON(response)
+	}
+}
+
+// parseBasicAuth will parse the client credentials of the basic authorization header.
+// The credentials are url encoded before base64 encoding as required by RFC 6749.
+func parseBasicAuth(header string) (clientId, clientSecret string, ok bool) {
+	encoded, found := strings.CutPrefix(header, "Basic ")
+	if !found {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+
+	id, secret, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return "", "", false
+	}
+
+	clientId, err = url.QueryUnescape(id)
+	if err != nil {
+		return "", "", false
+	}
+	clientSecret, err = url.QueryUnescape(secret)
+	if err != nil {
+		return "", "", false
+	}
+
+	return clientId, clientSecret, true
+}
+
+func NewDefaultOAuthHandler(oauthService services.OAuthService) *DefaultOAuthHandler {
+	return &DefaultOAuthHandler{oauthService}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"server/auth/oidc"
+	"server/logging"
+	"server/models"
+	"server/services"
+	"server/tracing"
+	"server/utils"
+	"time"
+)
+
+// oidcStateCookie is the name of the cookie holding the signed login state.
+const oidcStateCookie = "oidc_state"
+
+// oidcStateExpiration is how long the user has to log in at the provider.
+const oidcStateExpiration = time.Minute * 10
+
+// OIDCHandler interface handles the login with an external OpenID Connect provider.
+type OIDCHandler interface {
+	// Login handler used to redirect the user to the provider.
+	Login() fiber.Handler
+	// Callback handler used to finish the login when the provider redirects the user back.
+	Callback() fiber.Handler
+}
+
+// DefaultOIDCHandler is the default implementation of [OIDCHandler].
+type DefaultOIDCHandler struct {
+	provider    *oidc.Provider
+	userService services.UserService
+	stateSecret []byte
+}
+
+func (h *DefaultOIDCHandler) Login() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "OIDCHandler.Login")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		state, err := oidc.NewLoginState()
+		if err != nil {
+			utils.HandleErrorResponse(c, utils.InternalError(ctx, err))
+			return nil
+		}
+
+		exp := time.Now().Add(oidcStateExpiration)
+		value, err := oidc.SignState(*state, h.stateSecret, exp)
+		if err != nil {
+			utils.HandleErrorResponse(c, utils.InternalError(ctx, err))
+			return nil
+		}
+
+		// Lax is required, the cookie must be sent when the provider redirects the user back.
+		c.Cookie(&fiber.Cookie{
+			Name:     oidcStateCookie,
+			Value:    value,
+			Expires:  exp,
+			HTTPOnly: true,
+			Secure:   c.Protocol() == "https",
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+
+		return c.Redirect(h.provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier), fiber.StatusFound)
+	}
+}
+
+func (h *DefaultOIDCHandler) Callback() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "OIDCHandler.Callback")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		state, err := oidc.ParseState(c.Cookies(oidcStateCookie), h.stateSecret)
+		c.ClearCookie(oidcStateCookie)
+		if err != nil || c.Query("state") != state.State {
+			utils.HandleErrorResponse(c, utils.NewErrorResponse(utils.CodeInvalidLoginState, "Invalid login state", fiber.StatusBadRequest))
+			return nil
+		}
+
+		if c.Query("error") != "" {
+			utils.HandleErrorResponse(c, utils.NewErrorResponse(utils.CodeLoginFailed, "Login at the identity provider failed", fiber.StatusUnauthorized))
+			return nil
+		}
+
+		idToken, err := h.provider.Exchange(c.UserContext(), c.Query("code"), state.CodeVerifier, state.Nonce)
+		if err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "Error exchanging OIDC code", "error", err)
+			utils.HandleErrorResponse(c, utils.NewErrorResponse(utils.CodeInvalidCredentials, "Invalid credentials", fiber.StatusUnauthorized))
+			return nil
+		}
+
+		tokenGroup, errorResponse := h.userService.LoginWithIdentity(c.UserContext(), models.Identity{
+			Issuer:            h.provider.Issuer(),
+			Subject:           idToken.Subject,
+			Email:             idToken.Email,
+			EmailVerified:     bool(idToken.EmailVerified),
+			PreferredUsername: idToken.PreferredUsername,
+		})
+		if !utils.HandleErrorResponse(c, errorResponse) {
+			return nil
+		}
+
+		return c.JSON(tokenGroup)
+	}
+}
+
+func NewDefaultOIDCHandler(provider *oidc.Provider, userService services.UserService, stateSecret []byte) *DefaultOIDCHandler {
+	return &DefaultOIDCHandler{
+		provider:    provider,
+		userService: userService,
+		stateSecret: stateSecret,
+	}
+}
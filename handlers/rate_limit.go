@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"server/logging"
+	"server/ratelimit"
+	"server/utils"
+	"time"
+)
+
+// RateLimitMiddleware will limit the requests of a client ip for the action.
+// If countAll is true every request is counted, otherwise only the requests
+// that failed with [fiber.StatusUnauthorized] are counted.
+func RateLimitMiddleware(limiter ratelimit.Limiter, action string, countAll bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := action + ":ip:" + c.IP()
+		if !handleRateLimit(c, limiter, key) {
+			return nil
+		}
+
+		err := c.Next()
+		if countAll || c.Response().StatusCode() == fiber.StatusUnauthorized {
+			hitRateLimit(c, limiter, key)
+		}
+
+		return err
+	}
+}
+
+// handleRateLimit will return true if none of the keys is rate limited.
+// Otherwise, it responds with [fiber.StatusTooManyRequests] and Retry-After header.
+// If the limiter fails the request is allowed, so the API stays available.
+func handleRateLimit(c *fiber.Ctx, limiter ratelimit.Limiter, keys ...string) bool {
+	var wait time.Duration
+	for _, key := range keys {
+		delay, err := limiter.Check(c.UserContext(), key)
+		if err != nil {
+			logging.FromContext(c.UserContext()).ErrorContext(c.UserContext(), "Error checking rate limit", "error", err)
+			continue
+		}
+		wait = max(wait, delay)
+	}
+
+	if wait <= 0 {
+		return true
+	}
+
+	return utils.HandleErrorResponse(c, utils.TooManyRequestsErrorResponse(wait))
+}
+
+// hitRateLimit will record an attempt for all keys.
+func hitRateLimit(c *fiber.Ctx, limiter ratelimit.Limiter, keys ...string) {
+	for _, key := range keys {
+		if _, err := limiter.Hit(c.UserContext(), key); err != nil {
+			logging.FromContext(c.UserContext()).ErrorContext(c.UserContext(), "Error recording rate limit attempt", "error", err)
+		}
+	}
+}
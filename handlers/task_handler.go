@@ -6,6 +6,7 @@ import (
 	"server/auth/tokens"
 	"server/models"
 	"server/services"
+	"server/tracing"
 	"server/utils"
 )
 
@@ -31,12 +32,17 @@ type DefaultTaskHandler struct {
 
 func (h *DefaultTaskHandler) GetTasks() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "TaskHandler.GetTasks")
+		defer span.End()
+		c.SetUserContext(ctx)
+
 		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
 		if !ok {
 			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
 		}
 
-		tasks, err := h.taskService.GetTasks(c.Context(), *claims)
+		tasks, err := h.taskService.GetTasks(c.UserContext(), *claims)
 		if !utils.HandleErrorResponse(c, err) {
 			return nil
 		}
@@ -47,17 +53,22 @@ func (h *DefaultTaskHandler) GetTasks() fiber.Handler {
 
 func (h *DefaultTaskHandler) AddTask() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "TaskHandler.AddTask")
+		defer span.End()
+		c.SetUserContext(ctx)
+
 		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
 		if !ok {
 			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
 		}
 
 		var task models.NewTaskPayload
-		if err := c.BodyParser(&task); err != nil {
+		if ok, err := utils.ParseBody(c, &task); !ok {
 			return err
 		}
 
-		newTask, err := h.taskService.AddTask(c.Context(), *claims, &task)
+		newTask, err := h.taskService.AddTask(c.UserContext(), *claims, &task)
 		if !utils.HandleErrorResponse(c, err) {
 			return nil
 		}
@@ -68,12 +79,22 @@ func (h *DefaultTaskHandler) AddTask() fiber.Handler {
 
 func (h *DefaultTaskHandler) UpdateTask() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "TaskHandler.UpdateTask")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
 		var task models.TaskPayload
-		if err := c.BodyParser(&task); err != nil {
+		if ok, err := utils.ParseBody(c, &task); !ok {
 			return err
 		}
 
-		err := h.taskService.UpdateTask(c.Context(), &task)
+		err := h.taskService.UpdateTask(c.UserContext(), *claims, &task)
 		if !utils.HandleErrorResponse(c, err) {
 			return nil
 		}
@@ -85,14 +106,24 @@ func (h *DefaultTaskHandler) UpdateTask() fiber.Handler {
 
 func (h *DefaultTaskHandler) DeleteTask() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "TaskHandler.DeleteTask")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
 		id := c.Params("id")
 		parsedId, err := uuid.Parse(id)
 		if err != nil {
-			utils.HandleErrorResponse(c, utils.NewErrorResponse("Invalid uuid", fiber.StatusBadRequest))
+			utils.HandleErrorResponse(c, utils.NewErrorResponse(utils.CodeInvalidId, "Invalid uuid", fiber.StatusBadRequest))
 			return nil
 		}
 
-		errorResponse := h.taskService.DeleteTask(c.Context(), parsedId)
+		errorResponse := h.taskService.DeleteTask(c.UserContext(), *claims, parsedId)
 		if !utils.HandleErrorResponse(c, errorResponse) {
 			return nil
 		}
@@ -5,6 +5,7 @@ import (
 	"server/auth/tokens"
 	"server/models"
 	"server/services"
+	"server/tracing"
 	"server/utils"
 )
 
@@ -16,6 +17,26 @@ type UserHandler interface {
 	Login() fiber.Handler
 	// Refresh handler used to revalidate tokens.
 	Refresh() fiber.Handler
+	// ForgotPassword handler used to request a password reset token.
+	ForgotPassword() fiber.Handler
+	// ResetPassword handler used to set a new password with a reset token.
+	ResetPassword() fiber.Handler
+	// VerifyEmail handler used to confirm the email address with a verification token.
+	VerifyEmail() fiber.Handler
+	// ResendVerification handler used to request a new verification email.
+	ResendVerification() fiber.Handler
+	// ChangePassword handler used to change the password of the authenticated user.
+	ChangePassword() fiber.Handler
+	// ChangeEmail handler used to change the email of the authenticated user.
+	ChangeEmail() fiber.Handler
+	// ChangeUsername handler used to change the username of the authenticated user.
+	ChangeUsername() fiber.Handler
+	// DeleteAccount handler used to schedule the deletion of the authenticated user.
+	DeleteAccount() fiber.Handler
+	// ExportData handler used to download all data of the authenticated user.
+	ExportData() fiber.Handler
+	// GetActivity handler used to view the security events of the authenticated user.
+	GetActivity() fiber.Handler
 }
 
 // DefaultUserHandler interface is the default implementation of [UserHandler]
@@ -25,33 +46,37 @@ type DefaultUserHandler struct {
 
 func (h *DefaultUserHandler) Register() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.Register")
+		defer span.End()
+		c.SetUserContext(ctx)
+
 		var payload models.RegistrationsPayload
-		if err := c.BodyParser(&payload); err != nil {
+		if ok, err := utils.ParseBody(c, &payload); !ok {
 			return err
 		}
 
-		if !utils.HandlePayload(c, &payload) {
-			return nil
-		}
-
-		err := h.userService.Register(c.Context(), payload)
+		err := h.userService.Register(c.UserContext(), payload)
 		if !utils.HandleErrorResponse(c, err) {
 			return nil
 		}
 
-		c.Status(fiber.StatusBadRequest)
+		c.Status(fiber.StatusCreated)
 		return nil
 	}
 }
 
 func (h *DefaultUserHandler) Login() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.Login")
+		defer span.End()
+		c.SetUserContext(ctx)
+
 		var payload models.LoginPayload
-		if err := c.BodyParser(&payload); err != nil {
+		if ok, err := utils.ParseBody(c, &payload); !ok {
 			return err
 		}
 
-		tokenGroup, err := h.userService.Login(c.Context(), payload)
+		tokenGroup, err := h.userService.Login(c.UserContext(), payload)
 		if !utils.HandleErrorResponse(c, err) {
 			return nil
 		}
@@ -62,12 +87,17 @@ func (h *DefaultUserHandler) Login() fiber.Handler {
 
 func (h *DefaultUserHandler) Refresh() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.Refresh")
+		defer span.End()
+		c.SetUserContext(ctx)
+
 		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
 		if !ok {
 			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
 		}
 
-		tokenGroup, err := h.userService.Refresh(c.Context(), *claims)
+		tokenGroup, err := h.userService.Refresh(c.UserContext(), *claims)
 		if !utils.HandleErrorResponse(c, err) {
 			return nil
 		}
@@ -75,6 +105,245 @@ func (h *DefaultUserHandler) Refresh() fiber.Handler {
 	}
 }
 
+func (h *DefaultUserHandler) ForgotPassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.ForgotPassword")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		var payload models.ForgotPasswordPayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		err := h.userService.ForgotPassword(c.UserContext(), payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultUserHandler) ResetPassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.ResetPassword")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		var payload models.ResetPasswordPayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		err := h.userService.ResetPassword(c.UserContext(), payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultUserHandler) VerifyEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.VerifyEmail")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		var payload models.VerifyEmailPayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		err := h.userService.VerifyEmail(c.UserContext(), payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultUserHandler) ResendVerification() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.ResendVerification")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		var payload models.ResendVerificationPayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		err := h.userService.ResendVerification(c.UserContext(), payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultUserHandler) ChangePassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.ChangePassword")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		var payload models.ChangePasswordPayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		err := h.userService.ChangePassword(c.UserContext(), *claims, payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultUserHandler) ChangeEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.ChangeEmail")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		var payload models.ChangeEmailPayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		err := h.userService.ChangeEmail(c.UserContext(), *claims, payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultUserHandler) ChangeUsername() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.ChangeUsername")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		var payload models.ChangeUsernamePayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		err := h.userService.ChangeUsername(c.UserContext(), *claims, payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Status(fiber.StatusOK)
+		return nil
+	}
+}
+
+func (h *DefaultUserHandler) DeleteAccount() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.DeleteAccount")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		var payload models.DeleteAccountPayload
+		if ok, err := utils.ParseBody(c, &payload); !ok {
+			return err
+		}
+
+		deletion, err := h.userService.DeleteAccount(c.UserContext(), *claims, payload)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		return c.Status(fiber.StatusAccepted).JSON(deletion)
+	}
+}
+
+func (h *DefaultUserHandler) ExportData() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.ExportData")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		archive, err := h.userService.ExportData(c.UserContext(), *claims)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		c.Attachment("export.zip")
+		return c.Send(archive)
+	}
+}
+
+func (h *DefaultUserHandler) GetActivity() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracing.Start(c.UserContext(), "UserHandler.GetActivity")
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		claims, ok := c.Locals(tokens.JWTClaimsKey).(*tokens.Token)
+		if !ok {
+			utils.HandleErrorResponse(c, utils.InternalServerErrorResponse())
+			return nil
+		}
+
+		var query models.ActivityQuery
+		if ok, err := utils.ParseQuery(c, &query); !ok {
+			return err
+		}
+
+		events, err := h.userService.GetActivity(c.UserContext(), *claims, query)
+		if !utils.HandleErrorResponse(c, err) {
+			return nil
+		}
+
+		return c.JSON(events)
+	}
+}
+
 func NewDefaultUserHandler(userRepository services.UserService) *DefaultUserHandler {
 	return &DefaultUserHandler{
 		userService: userRepository,
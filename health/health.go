@@ -0,0 +1,124 @@
+// Package health checks the dependencies of the server for the readiness probe.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status is a custom type for the state of the server or a dependency.
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Check is a named function that checks one dependency. It returns an error if the dependency can't be used.
+type Check struct {
+	Name string
+	Func func(ctx context.Context) error
+}
+
+// Result struct holds the state of one dependency.
+type Result struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report struct holds the state of the server. The server is up only if every dependency is up.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs the checks of all dependencies.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+// Check will run all checks concurrently. Checks that don't finish within the timeout are down.
+func (c *Checker) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{
+		Status: StatusUp,
+		Checks: make(map[string]Result, len(c.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			result := Result{Status: StatusUp}
+			if err := check.Func(ctx); err != nil {
+				result.Status = StatusDown
+				result.Error = err.Error()
+			}
+			result.Duration = time.Since(start).String()
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status == StatusDown {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// DatabaseCheck will check that the database responds.
+func DatabaseCheck(db *sql.DB) Check {
+	return Check{
+		Name: "database",
+		Func: db.PingContext,
+	}
+}
+
+// MigrationCheck will check that the database schema is migrated at least to the expected version
+// and that the last migration didn't fail. A newer schema is accepted, so the replicas
+// of the previous release stay ready during a rolling update.
+func MigrationCheck(db *sql.DB, expected uint) Check {
+	return Check{
+		Name: "migrations",
+		Func: func(ctx context.Context) error {
+			var version uint
+			var dirty bool
+			err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty)
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("no migrations applied")
+			} else if err != nil {
+				return err
+			}
+
+			if dirty {
+				return fmt.Errorf("migration %d failed", version)
+			}
+			if version < expected {
+				return fmt.Errorf("schema version %d, expected %d", version, expected)
+			}
+			return nil
+		},
+	}
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
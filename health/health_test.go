@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerReportsEveryDependency(t *testing.T) {
+	checker := NewChecker(
+		time.Second,
+		Check{Name: "up", Func: func(context.Context) error { return nil }},
+		Check{Name: "down", Func: func(context.Context) error { return errors.New("unavailable") }},
+	)
+
+	report := checker.Check(context.Background())
+	if report.Status != StatusDown {
+		t.Fatalf("Expected the server to be down, got %s", report.Status)
+	}
+
+	if report.Checks["up"].Status != StatusUp {
+		t.Fatalf("Expected up dependency, got %+v", report.Checks["up"])
+	}
+	if down := report.Checks["down"]; down.Status != StatusDown || down.Error != "unavailable" {
+		t.Fatalf("Expected down dependency, got %+v", down)
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	checker := NewChecker(
+		time.Millisecond*10,
+		Check{Name: "slow", Func: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+
+	report := checker.Check(context.Background())
+	if report.Status != StatusDown {
+		t.Fatalf("Expected the slow dependency to be down, got %+v", report)
+	}
+}
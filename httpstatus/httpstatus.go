@@ -0,0 +1,37 @@
+package httpstatus
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+)
+
+// FromError will return the status of the response written for the request. Errors returned by the
+// handlers are written by the error handler after the middleware, so their status is derived the way
+// the error handler does: client errors of [fiber.Error] keep their code, malformed payloads are a 400
+// and every other error is a 500.
+// It isn't in utils, as utils imports the logging and tracing packages using it.
+func FromError(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) && fiberErr.Code < fiber.StatusInternalServerError {
+		return fiberErr.Code
+	}
+	if MalformedPayload(err) {
+		return fiber.StatusBadRequest
+	}
+
+	return fiber.StatusInternalServerError
+}
+
+// MalformedPayload will return true if the error is returned by the json decoder or the parser of
+// the query and form values, for handlers that parse the payload without utils.ParseBody.
+func MalformedPayload(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var multiErr fiber.MultiError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.As(err, &multiErr)
+}
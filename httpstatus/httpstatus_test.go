@@ -0,0 +1,48 @@
+package httpstatus
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"written response", nil, fiber.StatusCreated},
+		{"client error", fiber.ErrNotFound, fiber.StatusNotFound},
+		{"wrapped client error", errors.Join(errors.New("route"), fiber.ErrMethodNotAllowed), fiber.StatusMethodNotAllowed},
+		{"malformed json", json.Unmarshal([]byte("{"), &struct{}{}), fiber.StatusBadRequest},
+		{"server error", fiber.ErrServiceUnavailable, fiber.StatusInternalServerError},
+		{"other error", errors.New("failed"), fiber.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var status int
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				err := c.Next()
+				status = FromError(c, err)
+				return err
+			})
+			app.Get("/", func(c *fiber.Ctx) error {
+				c.Status(fiber.StatusCreated)
+				return test.err
+			})
+
+			if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+				t.Fatal(err)
+			}
+			if status != test.expected {
+				t.Fatalf("Expected %d, got %d", test.expected, status)
+			}
+		})
+	}
+}
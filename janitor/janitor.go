@@ -0,0 +1,115 @@
+// Package janitor removes expired data in the background.
+package janitor
+
+import (
+	"context"
+	"errors"
+	"server/logging"
+	"server/metrics"
+	"server/ratelimit"
+	"server/repositories"
+	"time"
+)
+
+// LockKey is the key of the postgres advisory lock held by the replica running the cleanup.
+const LockKey int64 = 0x7461736b6a616e
+
+// ErrSkipped is returned by [Janitor.RunOnce] if another replica holds the lock, so nothing was deleted.
+var ErrSkipped = errors.New("cleanup skipped, the lock is held by another replica")
+
+// Janitor periodically deletes expired tokens and rate limits in batches.
+// Only the replica that holds the lock runs the cleanup, the others skip it.
+type Janitor struct {
+	tokenRepository repositories.TokenRepository
+	limiter         ratelimit.Limiter
+	locker          Locker
+	interval        time.Duration
+	batchSize       int
+}
+
+// Run will clean up on every interval until the context is canceled.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := j.RunOnce(ctx)
+			if errors.Is(err, ErrSkipped) {
+				continue
+			}
+			if err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "Error deleting expired data", "error", err)
+				continue
+			}
+
+			if count > 0 {
+				logging.FromContext(ctx).InfoContext(ctx, "Deleted expired data", "count", count)
+			}
+		}
+	}
+}
+
+// RunOnce will delete all expired tokens and rate limits if the lock is acquired. It returns the number of deleted rows.
+// If the lock is held by another replica it returns [ErrSkipped].
+func (j *Janitor) RunOnce(ctx context.Context) (int64, error) {
+	unlock, acquired, err := j.locker.TryLock(ctx)
+	if err != nil {
+		metrics.CountJanitorRun(metrics.JanitorFailed)
+		return 0, err
+	}
+	if !acquired {
+		metrics.CountJanitorRun(metrics.JanitorSkipped)
+		return 0, ErrSkipped
+	}
+	defer unlock()
+
+	tokens, err := j.deleteExpired(ctx, "tokens", j.tokenRepository.DeleteExpiredTokens)
+	if err != nil {
+		metrics.CountJanitorRun(metrics.JanitorFailed)
+		return tokens, err
+	}
+
+	rateLimits, err := j.deleteExpired(ctx, "rate_limits", j.limiter.DeleteExpired)
+	if err != nil {
+		metrics.CountJanitorRun(metrics.JanitorFailed)
+		return tokens + rateLimits, err
+	}
+
+	metrics.CountJanitorRun(metrics.JanitorDone)
+	return tokens + rateLimits, nil
+}
+
+// deleteExpired will call deleteBatch until all expired rows of the data are deleted.
+// Deleting in batches keeps the transactions and row locks short.
+func (j *Janitor) deleteExpired(ctx context.Context, data string, deleteBatch func(context.Context, int) (int64, error)) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		count, err := deleteBatch(ctx, j.batchSize)
+		if err != nil {
+			return total, err
+		}
+
+		total += count
+		metrics.CountJanitorDeleted(data, count)
+
+		if count < int64(j.batchSize) {
+			break
+		}
+	}
+
+	return total, ctx.Err()
+}
+
+func NewJanitor(tokenRepository repositories.TokenRepository, limiter ratelimit.Limiter, locker Locker, interval time.Duration, batchSize int) *Janitor {
+	return &Janitor{
+		tokenRepository: tokenRepository,
+		limiter:         limiter,
+		locker:          locker,
+		interval:        interval,
+		batchSize:       batchSize,
+	}
+}
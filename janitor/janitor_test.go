@@ -0,0 +1,93 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"server/ratelimit"
+	"server/repositories"
+	"testing"
+	"time"
+)
+
+// fakeTokenRepository has a number of expired tokens.
+type fakeTokenRepository struct {
+	repositories.TokenRepository
+	expired int64
+	calls   int
+}
+
+func (r *fakeTokenRepository) DeleteExpiredTokens(_ context.Context, limit int) (int64, error) {
+	r.calls++
+	count := min(r.expired, int64(limit))
+	r.expired -= count
+	return count, nil
+}
+
+// fakeLimiter has a number of expired keys.
+type fakeLimiter struct {
+	ratelimit.Limiter
+	expired int64
+}
+
+func (l *fakeLimiter) DeleteExpired(_ context.Context, limit int) (int64, error) {
+	count := min(l.expired, int64(limit))
+	l.expired -= count
+	return count, nil
+}
+
+// fakeLocker is held by another replica if locked is true.
+type fakeLocker struct {
+	locked   bool
+	unlocked bool
+}
+
+func (l *fakeLocker) TryLock(context.Context) (func(), bool, error) {
+	if l.locked {
+		return nil, false, nil
+	}
+
+	l.locked = true
+	return func() {
+		l.locked = false
+		l.unlocked = true
+	}, true, nil
+}
+
+func TestRunOnceDeletesInBatches(t *testing.T) {
+	repository := &fakeTokenRepository{expired: 25}
+	limiter := &fakeLimiter{expired: 12}
+	locker := &fakeLocker{}
+	janitor := NewJanitor(repository, limiter, locker, time.Minute, 10)
+
+	count, err := janitor.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 37 {
+		t.Fatalf("Expected 37 deleted rows, got %d", count)
+	}
+	if repository.calls != 3 {
+		t.Fatalf("Expected 3 batches of tokens, got %d", repository.calls)
+	}
+	if limiter.expired != 0 {
+		t.Fatalf("Expected all expired rate limits to be deleted, %d left", limiter.expired)
+	}
+	if !locker.unlocked {
+		t.Fatal("Expected the lock to be released")
+	}
+}
+
+func TestRunOnceSkipsWithoutLock(t *testing.T) {
+	repository := &fakeTokenRepository{expired: 5}
+	janitor := NewJanitor(repository, &fakeLimiter{}, &fakeLocker{locked: true}, time.Minute, 10)
+
+	count, err := janitor.RunOnce(context.Background())
+	if !errors.Is(err, ErrSkipped) {
+		t.Fatalf("Expected %v, got %v", ErrSkipped, err)
+	}
+
+	if count != 0 || repository.calls != 0 {
+		t.Fatalf("Expected the cleanup to be skipped, deleted %d tokens", count)
+	}
+}
@@ -0,0 +1,57 @@
+package janitor
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"server/logging"
+)
+
+// Locker interface elects the replica that runs the cleanup.
+type Locker interface {
+	// TryLock will try to acquire the lock without waiting. If the lock is acquired
+	// the returned function must be called to release it.
+	TryLock(ctx context.Context) (unlock func(), acquired bool, err error)
+}
+
+// PostgresLocker is implementation of [Locker] using postgres advisory lock.
+// The lock is held by a database session, so it is released also if the replica crashes.
+type PostgresLocker struct {
+	db  *sql.DB
+	key int64
+}
+
+func (l *PostgresLocker) TryLock(ctx context.Context) (func(), bool, error) {
+	// Advisory locks belong to the session, so the lock and unlock must use the same connection.
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&acquired)
+	if err != nil || !acquired {
+		_ = conn.Close()
+		return nil, false, err
+	}
+
+	unlock := func() {
+		// The lock is released even if the context of the run is canceled.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "Error releasing janitor lock", "error", err)
+			// The session may still hold the lock, so the connection is discarded instead of returned to the pool.
+			// Closing the session releases the lock.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		_ = conn.Close()
+	}
+
+	return unlock, true, nil
+}
+
+func NewPostgresLocker(db *sql.DB, key int64) *PostgresLocker {
+	return &PostgresLocker{
+		db:  db,
+		key: key,
+	}
+}
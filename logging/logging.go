@@ -0,0 +1,62 @@
+// Package logging provides the structured logger of the server and carries it in the context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"server/config"
+	"strings"
+)
+
+// loggerKey is the key of the logger in the context.
+type loggerKey struct{}
+
+// requestIdKey is the key of the request id in the context.
+type requestIdKey struct{}
+
+// New will create the logger writing records in the configured format and level.
+func New(conf *config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(conf.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", conf.Level)
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(conf.Format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", conf.Format)
+	}
+}
+
+// WithLogger will return a copy of the context carrying the logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext will return the logger carried by the context. The default logger is returned
+// if the context doesn't carry one.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// WithRequestId will return a copy of the context carrying the id of the request.
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+// RequestIdFromContext will return the id of the request carried by the context.
+// Empty string is returned if the context doesn't carry one.
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"server/httpstatus"
+	"time"
+)
+
+// RequestIdHeader is the header carrying the id of the request.
+const RequestIdHeader = "X-Request-ID"
+
+// maxRequestIdLength is the maximal length of request id accepted from the client.
+const maxRequestIdLength = 128
+
+// validRequestId will return true if the request id of the client can be logged safely.
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+
+	for _, r := range requestId {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Middleware will assign an id to the request and carry a logger with the id in the user context.
+// The id sent by the client or a proxy in [RequestIdHeader] is reused, otherwise a new one is generated.
+// The id is returned in the same header. After the request is handled an access log is written.
+func Middleware(logger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		requestId := c.Get(RequestIdHeader)
+		if !validRequestId(requestId) {
+			requestId = uuid.NewString()
+		}
+		c.Set(RequestIdHeader, requestId)
+
+		requestLogger := logger.With(slog.String("request_id", requestId))
+		if span := trace.SpanContextFromContext(c.UserContext()); span.IsValid() {
+			requestLogger = requestLogger.With(slog.String("trace_id", span.TraceID().String()))
+		}
+
+		ctx := WithRequestId(c.UserContext(), requestId)
+		c.SetUserContext(WithLogger(ctx, requestLogger))
+
+		err := c.Next()
+
+		status := httpstatus.FromError(c, err)
+
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		requestLogger.LogAttrs(
+			c.UserContext(),
+			level,
+			"Request handled",
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.String("route", c.Route().Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("ip", c.IP()),
+		)
+
+		return err
+	}
+}
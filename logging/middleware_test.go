@@ -0,0 +1,82 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"server/logging"
+	"server/utils"
+	"strings"
+	"testing"
+)
+
+func newApp(output io.Writer) *fiber.App {
+	app := fiber.New()
+	app.Use(logging.Middleware(slog.New(slog.NewJSONHandler(output, nil))))
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		utils.HandleErrorResponse(c, utils.InternalError(c.UserContext(), io.ErrUnexpectedEOF))
+		return nil
+	})
+	return app
+}
+
+func TestMiddlewareReusesRequestId(t *testing.T) {
+	var output bytes.Buffer
+	app := newApp(&output)
+
+	request := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	request.Header.Set(logging.RequestIdHeader, "client-request-1")
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id := response.Header.Get(logging.RequestIdHeader); id != "client-request-1" {
+		t.Fatalf("Expected the request id of the client, got %q", id)
+	}
+
+	var body utils.Problem
+	if err = json.NewDecoder(response.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.RequestId != "client-request-1" {
+		t.Fatalf("Expected the request id in the error body, got %q", body.RequestId)
+	}
+
+	// The underlying error and the access log are written with the request id.
+	logs := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 log records, got %d:\n%s", len(logs), output.String())
+	}
+	for _, record := range logs {
+		if !strings.Contains(record, `"request_id":"client-request-1"`) {
+			t.Fatalf("Expected the record to contain the request id, got %s", record)
+		}
+	}
+	if !strings.Contains(logs[0], `"error":"unexpected EOF"`) {
+		t.Fatalf("Expected the underlying error to be logged, got %s", logs[0])
+	}
+	if !strings.Contains(logs[1], `"level":"ERROR"`) || !strings.Contains(logs[1], `"status":500`) {
+		t.Fatalf("Expected the access log of the server error, got %s", logs[1])
+	}
+}
+
+func TestMiddlewareGeneratesRequestId(t *testing.T) {
+	app := newApp(io.Discard)
+
+	request := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	request.Header.Set(logging.RequestIdHeader, "invalid id\n")
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := response.Header.Get(logging.RequestIdHeader)
+	if id == "" || id == "invalid id\n" {
+		t.Fatalf("Expected a generated request id, got %q", id)
+	}
+}
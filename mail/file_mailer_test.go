@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailerSend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	mailer := NewFileMailer("no-reply@localhost", path)
+
+	for _, to := range []string{"first@example.com", "second@example.com"} {
+		err := mailer.Send(context.Background(), Message{To: to, Subject: "Subject", Body: "Body"})
+		if err != nil {
+			t.Fatalf("Error sending message: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading mail file: %v", err)
+	}
+
+	for _, expected := range []string{"To: first@example.com", "To: second@example.com", "From: no-reply@localhost"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Mail file doesn't contain %q", expected)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"server/config"
+	"server/logging"
+)
+
+// Message struct holds the data of a single email.
+type Message struct {
+	// To is the address of the recipient.
+	To string
+	// Subject is the subject line of the email.
+	Subject string
+	// Body is the plain text content of the email.
+	Body string
+}
+
+// Mailer interface is used to deliver emails to users.
+type Mailer interface {
+	// Send will deliver the message to its recipient.
+	Send(ctx context.Context, message Message) error
+}
+
+// LogMailer is implementation of [Mailer] that writes the messages to the log
+// instead of sending them. It is meant for local development.
+type LogMailer struct {
+	from string
+}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	logging.FromContext(ctx).InfoContext(ctx, "Mail sent", "from", m.from, "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from}
+}
+
+// NewMailer will create the [Mailer] selected by the configuration.
+func NewMailer(conf *config.MailConfig) (Mailer, error) {
+	switch conf.Backend {
+	case config.LogMailBackend:
+		return NewLogMailer(conf.From), nil
+	case config.FileMailBackend:
+		return NewFileMailer(conf.From, conf.FilePath), nil
+	default:
+		return nil, fmt.Errorf("unknown mail backend: %s", conf.Backend)
+	}
+}
@@ -0,0 +1,117 @@
+// Package metrics collects the prometheus metrics of the server.
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+// Registry holds all metrics of the server.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of handled HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of handled HTTP requests by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	authEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_events_total",
+		Help: "Number of authentication events, like logins and token refreshes, by type and outcome.",
+	}, []string{"type", "outcome"})
+
+	taskOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "task_operations_total",
+		Help: "Number of successful task operations by operation.",
+	}, []string{"operation"})
+
+	janitorRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "janitor_runs_total",
+		Help: "Number of cleanup runs of the janitor by outcome.",
+	}, []string{"outcome"})
+
+	janitorDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "janitor_deleted_total",
+		Help: "Number of expired rows deleted by the janitor by data.",
+	}, []string{"data"})
+)
+
+// The task operations counted by [CountTaskOperation].
+const (
+	TaskRead   = "read"
+	TaskAdd    = "add"
+	TaskUpdate = "update"
+	TaskDelete = "delete"
+)
+
+// The outcomes of the janitor runs counted by [CountJanitorRun].
+const (
+	// JanitorDone is a run that deleted the expired data.
+	JanitorDone = "done"
+	// JanitorSkipped is a run skipped because another replica held the lock.
+	JanitorSkipped = "skipped"
+	// JanitorFailed is a run that stopped on an error.
+	JanitorFailed = "error"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		authEvents,
+		taskOperations,
+		janitorRuns,
+		janitorDeleted,
+	)
+}
+
+// RegisterDB will add the connection pool stats of the database, like open and in-use connections,
+// wait count and wait duration.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// CountTaskOperation will count a successful task operation.
+func CountTaskOperation(operation string) {
+	taskOperations.WithLabelValues(operation).Inc()
+}
+
+// CountJanitorRun will count a cleanup run of the janitor.
+func CountJanitorRun(outcome string) {
+	janitorRuns.WithLabelValues(outcome).Inc()
+}
+
+// CountJanitorDeleted will count the expired rows of the data deleted by the janitor.
+func CountJanitorDeleted(data string, count int64) {
+	janitorDeleted.WithLabelValues(data).Add(float64(count))
+}
+
+// Handler will return the handler exposing the metrics. If the token is not empty,
+// requests must send it as a bearer token.
+func Handler(token string) http.Handler {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return handler
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, handler http.Handler, authorization string) (int, string) {
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	body, _ := io.ReadAll(recorder.Body)
+	return recorder.Code, string(body)
+}
+
+func TestMiddlewareLabelsRouteTemplate(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/metrics-test/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	for _, id := range []string{"1", "2"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/metrics-test/"+id, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, body := scrape(t, Handler(""), "")
+	expected := `http_requests_total{method="GET",route="/metrics-test/:id",status="204"} 2`
+	if !strings.Contains(body, expected) {
+		t.Fatalf("Expected metrics to contain %q, got:\n%s", expected, body)
+	}
+}
+
+func TestHandlerRequiresToken(t *testing.T) {
+	handler := Handler("metrics-token")
+
+	if status, _ := scrape(t, handler, ""); status != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d without token, got %d", http.StatusUnauthorized, status)
+	}
+	if status, _ := scrape(t, handler, "Bearer wrong"); status != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d with wrong token, got %d", http.StatusUnauthorized, status)
+	}
+	if status, _ := scrape(t, handler, "Bearer metrics-token"); status != http.StatusOK {
+		t.Fatalf("Expected status %d with token, got %d", http.StatusOK, status)
+	}
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"server/httpstatus"
+	"strconv"
+	"time"
+)
+
+// Middleware will count the requests and observe their latency. The route is the registered path,
+// like /api/v1/tasks/delete/:id, so the number of series doesn't grow with the ids. Requests that
+// don't match any route are labeled with the path of the last matched middleware.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := httpstatus.FromError(c, err)
+
+		labels := []string{c.Method(), c.Route().Path, strconv.Itoa(status)}
+		httpRequests.WithLabelValues(labels...).Inc()
+		httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"context"
+	"server/audit"
+	"server/models"
+)
+
+// CountingRecorder is implementation of [audit.Recorder] that counts the events
+// and passes them to the next recorder.
+type CountingRecorder struct {
+	next audit.Recorder
+}
+
+func (r *CountingRecorder) Record(ctx context.Context, event models.AuditEvent) {
+	authEvents.WithLabelValues(string(event.Type), string(event.Outcome)).Inc()
+	r.next.Record(ctx, event)
+}
+
+func NewCountingRecorder(next audit.Recorder) *CountingRecorder {
+	return &CountingRecorder{next}
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS password_reset_tokens
+(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    exp        TIMESTAMPTZ                                 NOT NULL,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE NOT NULL
+);
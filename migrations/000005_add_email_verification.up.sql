@@ -0,0 +1,13 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Users registered before the verification was introduced are trusted.
+UPDATE users
+SET email_verified = TRUE;
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens
+(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    exp        TIMESTAMPTZ                                 NOT NULL,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE NOT NULL
+);
@@ -0,0 +1,10 @@
+ALTER TABLE tokens
+    DROP CONSTRAINT IF EXISTS tokens_user_id_fkey,
+    ADD CONSTRAINT tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
+
+ALTER TABLE tasks
+    DROP CONSTRAINT IF EXISTS tasks_user_id_fkey,
+    ADD CONSTRAINT tasks_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS delete_after;
@@ -0,0 +1,10 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS delete_after TIMESTAMPTZ;
+
+ALTER TABLE tasks
+    DROP CONSTRAINT IF EXISTS tasks_user_id_fkey,
+    ADD CONSTRAINT tasks_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE tokens
+    DROP CONSTRAINT IF EXISTS tokens_user_id_fkey,
+    ADD CONSTRAINT tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
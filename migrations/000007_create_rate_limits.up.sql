@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS rate_limits
+(
+    key           VARCHAR(255) PRIMARY KEY,
+    attempts      INT         NOT NULL,
+    last_attempt  TIMESTAMPTZ NOT NULL,
+    blocked_until TIMESTAMPTZ
+);
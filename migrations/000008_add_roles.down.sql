@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS disabled,
+    DROP COLUMN IF EXISTS role;
@@ -0,0 +1,4 @@
+-- Emails and usernames are unique regardless of the case. The migration fails
+-- if there are existing users that differ only in the case, they must be merged manually.
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (LOWER(email));
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_key ON users (LOWER(username));
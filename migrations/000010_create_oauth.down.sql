@@ -0,0 +1,8 @@
+DELETE FROM tokens WHERE client_id IS NOT NULL;
+
+ALTER TABLE tokens
+    DROP COLUMN IF EXISTS scopes,
+    DROP COLUMN IF EXISTS client_id;
+
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
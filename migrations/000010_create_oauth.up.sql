@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS oauth_clients
+(
+    id            VARCHAR(64) PRIMARY KEY,
+    -- secret_hash is NULL for public clients, which authenticate only with PKCE.
+    secret_hash   VARCHAR(64),
+    name          VARCHAR(255)                                NOT NULL,
+    redirect_uris TEXT[]                                      NOT NULL,
+    owner_id      INT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    created_at    TIMESTAMPTZ                                 NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes
+(
+    code_hash      VARCHAR(64) PRIMARY KEY,
+    client_id      VARCHAR(64) REFERENCES oauth_clients (id) ON DELETE CASCADE NOT NULL,
+    user_id        INT REFERENCES users (id) ON DELETE CASCADE                 NOT NULL,
+    redirect_uri   TEXT                                                        NOT NULL,
+    scopes         TEXT                                                        NOT NULL,
+    code_challenge VARCHAR(128)                                                NOT NULL,
+    exp            TIMESTAMPTZ                                                 NOT NULL
+);
+
+-- Refresh tokens issued to clients are stored together with the first party sessions.
+ALTER TABLE tokens
+    ADD COLUMN IF NOT EXISTS client_id VARCHAR(64) REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS scopes    TEXT;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_identities
+(
+    issuer     VARCHAR(255)                                NOT NULL,
+    subject    VARCHAR(255)                                NOT NULL,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMPTZ                                 NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer, subject)
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS tokens_exp_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS tokens_exp_idx ON tokens (exp);
@@ -0,0 +1,16 @@
+-- The events stay append-only, but the personal data of erased users can be removed.
+-- An update is allowed only if it clears the identifier, ip and user agent and keeps everything else.
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND (NEW.id, NEW.created_at, NEW.event_type, NEW.outcome, NEW.actor_id, NEW.user_id, NEW.reason)
+            IS NOT DISTINCT FROM
+            (OLD.id, OLD.created_at, OLD.event_type, OLD.outcome, OLD.actor_id, OLD.user_id, OLD.reason)
+        AND NEW.identifier = '' AND NEW.ip = '' AND NEW.user_agent = '' THEN
+        RETURN NEW;
+    END IF;
+
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS rate_limits_last_attempt_idx;
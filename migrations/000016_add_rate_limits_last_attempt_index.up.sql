@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS rate_limits_last_attempt_idx ON rate_limits (last_attempt);
@@ -0,0 +1,2 @@
+ALTER TABLE tokens
+    ALTER COLUMN exp TYPE TIMESTAMP USING exp AT TIME ZONE current_setting('TIMEZONE');
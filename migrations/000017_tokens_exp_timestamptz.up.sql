@@ -0,0 +1,5 @@
+-- The expiration is compared with NOW() by the janitor, so it must be a point in time. The existing
+-- values are read in the time zone of the session, which should be the time zone of the application
+-- that wrote them. The index of the column is rebuilt.
+ALTER TABLE tokens
+    ALTER COLUMN exp TYPE TIMESTAMPTZ USING exp AT TIME ZONE current_setting('TIMEZONE');
@@ -0,0 +1,84 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// CommandUsage describes the commands run by [Migrator.Command].
+const CommandUsage = `  up              apply all pending migrations
+  down [steps]    revert the last migrations (default 1)
+  goto <version>  apply or revert migrations until the version, 0 reverts all
+  force <version> set the version without migrating after a failed migration was fixed
+  status          show the applied and pending migrations
+`
+
+// Command will run the migration command with its arguments as given on the command line.
+// The output of the command is written to w.
+func (m *Migrator) Command(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New("expected a migration command and at most one argument")
+	}
+
+	command, argument := args[0], ""
+	if len(args) == 2 {
+		argument = args[1]
+	}
+
+	switch command {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if argument != "" {
+			var err error
+			if steps, err = strconv.Atoi(argument); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", argument)
+			}
+		}
+		return m.Down(ctx, steps)
+	case "goto", "force":
+		version, err := strconv.ParseUint(argument, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", argument)
+		}
+		if command == "goto" {
+			return m.Goto(ctx, uint(version))
+		}
+		return m.Force(ctx, uint(version))
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return status.Print(w)
+	default:
+		return errors.New("unknown migration command " + command)
+	}
+}
+
+// Print will write the version of the schema and a row for every migration.
+func (s *Status) Print(w io.Writer) error {
+	fmt.Fprintf(w, "Version: %d of %d", s.Version, s.Latest)
+	if s.Dirty {
+		fmt.Fprint(w, " (dirty)")
+	}
+	fmt.Fprintln(w)
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tSTATE")
+	for _, migration := range s.Migrations {
+		state := "pending"
+		if migration.Modified {
+			state = "modified"
+		} else if migration.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", migration.Version, migration.Name, state)
+	}
+	return writer.Flush()
+}
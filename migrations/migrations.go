@@ -0,0 +1,113 @@
+// Package migrations embeds the migrations of the database schema and applies them.
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FS holds the migration files. Their names are VERSION_NAME.up.sql and VERSION_NAME.down.sql.
+//
+//go:embed *.sql
+var FS embed.FS
+
+// Migration struct holds a single version of the schema.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum will return the sha256 of the up migration. It is stored when the migration is applied,
+// so edits of applied migrations are detected.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// Load will read the migrations from the files sorted by the version. Every up migration
+// must have a down migration.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		base := strings.TrimSuffix(name, ".up.sql")
+		version, err := parseVersion(base)
+		if err != nil {
+			return nil, err
+		}
+
+		up, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		down, err := fs.ReadFile(fsys, base+".down.sql")
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has no down migration: %w", base, err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    base,
+			Up:      string(up),
+			Down:    string(down),
+		})
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return int(a.Version) - int(b.Version)
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", migrations[i-1].Name, migrations[i].Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// parseVersion will parse the version prefix of the migration name.
+func parseVersion(name string) (uint, error) {
+	prefix, _, found := strings.Cut(name, "_")
+	if !found {
+		return 0, fmt.Errorf("migration %s has no version", name)
+	}
+
+	version, err := strconv.ParseUint(prefix, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("migration %s has invalid version: %w", name, err)
+	}
+
+	return uint(version), nil
+}
+
+// LatestVersion will return the version of the newest migration.
+func LatestVersion() (uint, error) {
+	names, err := fs.Glob(FS, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, name := range names {
+		version, err := parseVersion(name)
+		if err != nil {
+			return 0, err
+		}
+
+		latest = max(latest, version)
+	}
+
+	return latest, nil
+}
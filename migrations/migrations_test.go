@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLatestVersion(t *testing.T) {
+	version, err := LatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, _ := fs.Glob(FS, "*.up.sql")
+	if version != uint(len(names)) {
+		t.Fatalf("Expected latest version %d, got %d", len(names), version)
+	}
+}
+
+func TestEveryMigrationHasDown(t *testing.T) {
+	migrations, err := Load(FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, migration := range migrations {
+		if strings.TrimSpace(migration.Down) == "" {
+			t.Fatalf("Expected %s to have down migration", migration.Name)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000010_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"000010_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"000002_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"000002_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 2 || migrations[1].Version != 10 {
+		t.Fatalf("Expected migrations sorted by version, got %+v", migrations)
+	}
+	if migrations[0].Name != "000002_first" || migrations[0].Down != "DROP TABLE a;" {
+		t.Fatalf("Expected the files of the first migration, got %+v", migrations[0])
+	}
+
+	// The checksum covers the up migration, so any edit is detected.
+	edited := migrations[0]
+	edited.Up += "\n"
+	if edited.Checksum() == migrations[0].Checksum() {
+		t.Fatal("Expected the checksum to change with the migration")
+	}
+
+	delete(fsys, "000010_second.down.sql")
+	if _, err = Load(fsys); err == nil {
+		t.Fatal("Expected error for migration without down migration")
+	}
+
+	fsys["000010_second.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE b;")}
+	fsys["000002_other.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	fsys["000002_other.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	if _, err = Load(fsys); err == nil {
+		t.Fatal("Expected error for duplicated version")
+	}
+}
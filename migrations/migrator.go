@@ -0,0 +1,403 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io/fs"
+	"server/logging"
+	"strings"
+)
+
+// LockKey is the key of the advisory lock held while migrating, so replicas started together
+// don't apply the same migration twice.
+const LockKey int64 = 0x6d6967726174
+
+var (
+	// ErrDirty is returned when a migration applied by the migrate binary failed. The schema must be fixed
+	// manually and the version set with [Migrator.Force].
+	ErrDirty = errors.New("database is dirty")
+	// ErrChecksumMismatch is returned when an applied migration was edited. Applied migrations must never
+	// change, a new migration must be added instead.
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+)
+
+// MigrationStatus struct holds the state of a single migration in the database.
+type MigrationStatus struct {
+	Migration
+	Applied bool
+	// Modified is true if the migration was edited after it was applied.
+	Modified bool
+}
+
+// Status struct holds the state of the schema.
+type Status struct {
+	// Version is the version of the last applied migration or 0 if none is applied.
+	Version    uint
+	Dirty      bool
+	Latest     uint
+	Migrations []MigrationStatus
+}
+
+// Migrator applies the migrations to the database. The version is stored in schema_migrations in the same
+// format as the migrate binary used before, so already migrated databases are picked up. Checksums of the
+// applied migrations are stored in schema_migrations_checksums.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// Up will apply all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		// A newer build may have migrated the database already during a rolling deploy,
+		// so the schema is never reverted by Up.
+		if version >= m.latest() {
+			return nil
+		}
+		return m.migrate(ctx, conn, version, m.latest())
+	})
+}
+
+// Down will revert the given number of the last applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		index := m.index(version)
+		if index < 0 && version > 0 {
+			return fmt.Errorf("database version %d is unknown to this build", version)
+		}
+
+		var target uint
+		if index-steps >= 0 {
+			target = m.migrations[index-steps].Version
+		}
+		return m.migrate(ctx, conn, version, target)
+	})
+}
+
+// Goto will apply or revert the migrations until the schema is at the version. Version 0 reverts all migrations.
+func (m *Migrator) Goto(ctx context.Context, target uint) error {
+	if target != 0 && m.index(target) < 0 {
+		return fmt.Errorf("migration %d doesn't exist", target)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if version > 0 && m.index(version) < 0 {
+			return fmt.Errorf("database version %d is unknown to this build", version)
+		}
+		return m.migrate(ctx, conn, version, target)
+	})
+}
+
+// Force will set the version without applying any migration and clear the dirty flag. It is used
+// after a failed migration was fixed manually.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err = setVersion(ctx, tx, version); err != nil {
+			return err
+		}
+		// The checksums of the reverted versions are recorded again by the next run.
+		if _, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations_checksums WHERE version > $1`, version); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// Status will return the state of the schema and of every migration.
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	status := &Status{Latest: m.latest()}
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		var err error
+		status.Version, status.Dirty, err = readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		checksums, err := readChecksums(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			checksum, recorded := checksums[migration.Version]
+			status.Migrations = append(status.Migrations, MigrationStatus{
+				Migration: migration,
+				Applied:   migration.Version <= status.Version,
+				Modified:  recorded && checksum != migration.Checksum(),
+			})
+		}
+		return nil
+	})
+
+	return status, err
+}
+
+// withLock will run the function on a connection holding the migration lock. The tables
+// of the migrator are created if they don't exist.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	// Advisory locks belong to the session, so everything must run on the same connection.
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, LockKey); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, LockKey); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "Error releasing migration lock", "error", err)
+			// The session may still hold the lock and the next migration would wait for it forever, so
+			// the connection is discarded instead of returned to the pool. Closing the session releases the lock.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	_, err = conn.ExecContext(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations
+		(
+			version BIGINT  NOT NULL PRIMARY KEY,
+			dirty   BOOLEAN NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS schema_migrations_checksums
+		(
+			version    BIGINT PRIMARY KEY,
+			name       TEXT        NOT NULL,
+			checksum   VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// verify will return the current version if the database is clean and no applied migration was modified.
+// Migrations applied by the migrate binary have no checksum yet, so their current checksum is recorded.
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (uint, error) {
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d", ErrDirty, version)
+	}
+
+	checksums, err := readChecksums(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	var modified []string
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			break
+		}
+
+		checksum, recorded := checksums[migration.Version]
+		if !recorded {
+			if err = recordChecksum(ctx, conn, migration); err != nil {
+				return 0, err
+			}
+		} else if checksum != migration.Checksum() {
+			modified = append(modified, migration.Name)
+		}
+	}
+
+	if len(modified) > 0 {
+		return 0, fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(modified, ", "))
+	}
+	return version, nil
+}
+
+// migrate will apply the migrations from the version to the target, up or down.
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, version, target uint) error {
+	if target > version {
+		for _, migration := range m.migrations {
+			if migration.Version > version && migration.Version <= target {
+				if err := m.apply(ctx, conn, migration, true, migration.Version); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version > version || migration.Version <= target {
+			continue
+		}
+
+		var previous uint
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		if err := m.apply(ctx, conn, migration, false, previous); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply will run the up or down migration and set the version in one transaction,
+// so a failed migration leaves the schema at the previous version.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool, version uint) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query, direction := migration.Up, "up"
+	if !up {
+		query, direction = migration.Down, "down"
+	}
+	if strings.TrimSpace(query) != "" {
+		if _, err = tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("migration %s %s: %w", migration.Name, direction, err)
+		}
+	}
+
+	if err = setVersion(ctx, tx, version); err != nil {
+		return err
+	}
+	if up {
+		err = recordChecksum(ctx, tx, migration)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations_checksums WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, "Applied migration", "name", migration.Name, "direction", direction)
+	return nil
+}
+
+// latest will return the version of the newest migration or 0 if there are none.
+func (m *Migrator) latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// index will return the index of the migration with the version or -1 if it doesn't exist.
+func (m *Migrator) index(version uint) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// querier is implemented by both [sql.Conn] and [sql.Tx].
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// readVersion will read the version of the schema. No row means that no migration is applied.
+func readVersion(ctx context.Context, q querier) (uint, bool, error) {
+	var version uint
+	var dirty bool
+	err := q.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// setVersion will replace the version of the schema. Version 0 removes the row, as the migrate binary does.
+func setVersion(ctx context.Context, q querier, version uint) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, version)
+	return err
+}
+
+// readChecksums will read the checksums of the applied migrations by their version.
+func readChecksums(ctx context.Context, q querier) (map[uint]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, checksum FROM schema_migrations_checksums`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checksums := map[uint]string{}
+	for rows.Next() {
+		var version uint
+		var checksum string
+		if err = rows.Scan(&version, &checksum); err != nil {
+			return nil, err
+		}
+		checksums[version] = checksum
+	}
+	return checksums, rows.Err()
+}
+
+// recordChecksum will store the checksum of the applied migration.
+func recordChecksum(ctx context.Context, q querier, migration Migration) error {
+	_, err := q.ExecContext(
+		ctx,
+		`INSERT INTO schema_migrations_checksums (version, name, checksum)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (version) DO UPDATE SET name = EXCLUDED.name, checksum = EXCLUDED.checksum, applied_at = NOW()`,
+		migration.Version,
+		migration.Name,
+		migration.Checksum(),
+	)
+	return err
+}
+
+// NewMigrator will load the migrations from the files. [FS] holds the migrations of the server.
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"server/utils"
+	"server/validation"
+)
+
+// Profile struct holds the user data that is shown to the user.
+type Profile struct {
+	Id            int    `json:"id"`
+	Email         string `json:"email"`
+	Username      string `json:"username"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// NewProfile will create [Profile] from the [User].
+func NewProfile(user User) *Profile {
+	return &Profile{
+		Id:            user.Id,
+		Email:         user.Email,
+		Username:      user.Username,
+		EmailVerified: user.EmailVerified,
+	}
+}
+
+// Session struct holds the information about a refresh token of the user.
+type Session struct {
+	Id        uuid.UUID `json:"id"`
+	ExpiresAt ISOTime   `json:"expires_at"`
+}
+
+// AccountDeletion struct holds the time when the account of the user will be deleted.
+type AccountDeletion struct {
+	DeleteAfter ISOTime `json:"delete_after"`
+}
+
+// DeleteAccountPayload is a struct holding the password that confirms the account deletion.
+type DeleteAccountPayload struct {
+	Password string `json:"password"`
+}
+
+func (p *DeleteAccountPayload) ValidatePayload() *utils.ErrorResponse {
+	return validation.New().Required("password", p.Password).ErrorResponse()
+}
+
+// Identity struct holds the account of the user at an external identity provider.
+type Identity struct {
+	// Issuer is the url of the identity provider.
+	Issuer string
+	// Subject is the id of the account at the identity provider.
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
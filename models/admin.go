@@ -0,0 +1,66 @@
+package models
+
+import (
+	"server/utils"
+	"server/validation"
+)
+
+// AdminUser struct holds the user data that is shown to admins.
+type AdminUser struct {
+	Id            int      `json:"id"`
+	Email         string   `json:"email"`
+	Username      string   `json:"username"`
+	EmailVerified bool     `json:"email_verified"`
+	Role          Role     `json:"role"`
+	Disabled      bool     `json:"disabled"`
+	DeleteAfter   *ISOTime `json:"delete_after,omitempty"`
+}
+
+// NewAdminUser will create [AdminUser] from the [User].
+func NewAdminUser(user User) *AdminUser {
+	adminUser := &AdminUser{
+		Id:            user.Id,
+		Email:         user.Email,
+		Username:      user.Username,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		Disabled:      user.Disabled,
+	}
+
+	if user.DeleteAfter != nil {
+		adminUser.DeleteAfter = &ISOTime{*user.DeleteAfter}
+	}
+
+	return adminUser
+}
+
+// UserSearchQuery is a struct holding the query params of the users search.
+type UserSearchQuery struct {
+	// Query is matched against the email and the username. Empty query returns all users.
+	Query  string `query:"query"`
+	Limit  int    `query:"limit"`
+	Offset int    `query:"offset"`
+}
+
+func (q *UserSearchQuery) ValidatePayload() *utils.ErrorResponse {
+	v := validation.New()
+	validatePage(v, &q.Limit, q.Offset)
+	return v.ErrorResponse()
+}
+
+// validatePage will check the limit and offset of a paginated query.
+// Zero limit is replaced by the default of 50.
+func validatePage(v *validation.Validator, limit *int, offset int) {
+	if *limit == 0 {
+		*limit = 50
+	}
+
+	v.Range("limit", *limit, 1, 100)
+	v.Min("offset", offset, 0)
+}
+
+// TaskCounts struct holds the number of tasks of a user.
+type TaskCounts struct {
+	Total      int            `json:"total"`
+	ByPriority map[string]int `json:"by_priority"`
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"server/utils"
+	"server/validation"
+	"time"
+)
+
+// AuditEventType is a custom type for the type of security events.
+type AuditEventType string
+
+// The types of recorded events. Events of other users' accounts, like disabling an account by an admin,
+// have different actor and user.
+const (
+	AuditRegister             AuditEventType = "register"
+	AuditLogin                AuditEventType = "login"
+	AuditLoginSSO             AuditEventType = "login_sso"
+	AuditRefresh              AuditEventType = "refresh"
+	AuditPasswordResetRequest AuditEventType = "password_reset_request"
+	AuditPasswordReset        AuditEventType = "password_reset"
+	AuditPasswordChange       AuditEventType = "password_change"
+	AuditEmailVerify          AuditEventType = "email_verify"
+	AuditEmailChange          AuditEventType = "email_change"
+	AuditUsernameChange       AuditEventType = "username_change"
+	AuditTokensRevoked        AuditEventType = "tokens_revoked"
+	AuditAccountDeletion      AuditEventType = "account_deletion"
+	AuditAccountDisabled      AuditEventType = "account_disabled"
+	AuditAccountEnabled       AuditEventType = "account_enabled"
+)
+
+// AuditOutcome is a custom type for the result of security events.
+type AuditOutcome string
+
+// The outcomes of recorded events.
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditEvent struct holds a recorded security event.
+type AuditEvent struct {
+	Id   int64          `json:"id"`
+	Time ISOTime        `json:"time"`
+	Type AuditEventType `json:"type"`
+	// Outcome is the result of the event.
+	Outcome AuditOutcome `json:"outcome"`
+	// ActorId is the id of the user that caused the event. It is nil if the user is not known, for example failed login.
+	ActorId *int `json:"actor_id,omitempty"`
+	// UserId is the id of the user whose account is affected.
+	UserId *int `json:"user_id,omitempty"`
+	// Identifier is the email or username used when the user is not known.
+	Identifier string `json:"identifier,omitempty"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"user_agent"`
+	// Reason describes why the event failed or other details.
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewAuditEvent will create successful [AuditEvent] of the user that acted on their own account.
+// The user id is zero if the user is not known.
+func NewAuditEvent(eventType AuditEventType, userId int) AuditEvent {
+	event := AuditEvent{
+		Type:    eventType,
+		Outcome: AuditSuccess,
+	}
+
+	if userId != 0 {
+		event.ActorId = &userId
+		event.UserId = &userId
+	}
+
+	return event
+}
+
+// ActivityQuery is a struct holding the query params of the activity of the user.
+type ActivityQuery struct {
+	Limit  int `query:"limit"`
+	Offset int `query:"offset"`
+}
+
+func (q *ActivityQuery) ValidatePayload() *utils.ErrorResponse {
+	v := validation.New()
+	validatePage(v, &q.Limit, q.Offset)
+	return v.ErrorResponse()
+}
+
+// AuditQuery is a struct holding the filters of the audit events search.
+type AuditQuery struct {
+	UserId  int            `query:"user_id"`
+	ActorId int            `query:"actor_id"`
+	Type    AuditEventType `query:"type"`
+	Outcome AuditOutcome   `query:"outcome"`
+	IP      string         `query:"ip"`
+	// From and To are RFC 3339 times limiting the time of the events.
+	From   string `query:"from"`
+	To     string `query:"to"`
+	Limit  int    `query:"limit"`
+	Offset int    `query:"offset"`
+
+	// FromTime and ToTime are parsed from From and To by ValidatePayload. They are zero if not set.
+	FromTime time.Time `query:"-"`
+	ToTime   time.Time `query:"-"`
+}
+
+func (q *AuditQuery) ValidatePayload() *utils.ErrorResponse {
+	v := validation.New()
+	v.Time("from", q.From, &q.FromTime)
+	v.Time("to", q.To, &q.ToTime).TimeRange("from", q.FromTime, "to", q.ToTime)
+	v.Enum("outcome", string(q.Outcome), string(AuditSuccess), string(AuditFailure))
+	validatePage(v, &q.Limit, q.Offset)
+	return v.ErrorResponse()
+}
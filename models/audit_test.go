@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuditQueryValidatePayload(t *testing.T) {
+	query := AuditQuery{From: "2024-01-01T00:00:00Z", Outcome: AuditFailure}
+	if err := query.ValidatePayload(); err != nil {
+		t.Fatalf("Expected the query to be valid, got %v", err)
+	}
+
+	if !query.FromTime.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected from time to be parsed, got %v", query.FromTime)
+	}
+	if !query.ToTime.IsZero() {
+		t.Fatalf("Expected to time to be zero, got %v", query.ToTime)
+	}
+	if query.Limit != 50 {
+		t.Fatalf("Expected default limit 50, got %d", query.Limit)
+	}
+
+	invalid := []AuditQuery{
+		{From: "yesterday"},
+		{To: "2024-01-01"},
+		{Outcome: "unknown"},
+		{Limit: 101},
+	}
+	for _, q := range invalid {
+		if err := q.ValidatePayload(); err == nil {
+			t.Fatalf("Expected %+v to be invalid", q)
+		}
+	}
+}
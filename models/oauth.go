@@ -0,0 +1,196 @@
+package models
+
+import (
+	"server/utils"
+	"server/validation"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// ScopeTasksRead allows the client to read the tasks of the user.
+	ScopeTasksRead = "tasks:read"
+	// ScopeTasksWrite allows the client to add, update and delete the tasks of the user.
+	ScopeTasksWrite = "tasks:write"
+)
+
+// OAuthScopes are all scopes that can be granted to clients.
+var OAuthScopes = []string{ScopeTasksRead, ScopeTasksWrite}
+
+// ParseScopes will split the space separated scope string and check with the validator that at least
+// one scope is requested and every scope is known. Duplicated scopes are removed.
+func ParseScopes(v *validation.Validator, field, scope string) []string {
+	scopes := strings.Fields(scope)
+	v.Check(field, len(scopes) > 0, utils.FieldRequired, "At least one scope is required")
+
+	result := make([]string, 0, len(OAuthScopes))
+	for _, s := range scopes {
+		v.Enum(field, s, OAuthScopes...)
+		if slices.Contains(OAuthScopes, s) && !slices.Contains(result, s) {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
+// OAuthClient struct holds the data of a third party client.
+type OAuthClient struct {
+	Id string
+	// SecretHash is the hash of the client secret. It is empty for public clients.
+	SecretHash   string
+	Name         string
+	RedirectURIs []string
+	OwnerId      int
+	CreatedAt    time.Time
+}
+
+// Confidential will return true if the client authenticates with a secret.
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// HasRedirectURI will return true if the redirect uri is registered for the client.
+// The uris must match exactly.
+func (c *OAuthClient) HasRedirectURI(redirectURI string) bool {
+	for _, uri := range c.RedirectURIs {
+		if uri == redirectURI {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientInfo struct holds the client data that is shown to the users.
+type ClientInfo struct {
+	Id           string   `json:"client_id"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Confidential bool     `json:"confidential"`
+	CreatedAt    ISOTime  `json:"created_at"`
+}
+
+// NewClientInfo will create [ClientInfo] from the [OAuthClient].
+func NewClientInfo(client OAuthClient) *ClientInfo {
+	return &ClientInfo{
+		Id:           client.Id,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Confidential: client.Confidential(),
+		CreatedAt:    ISOTime{client.CreatedAt},
+	}
+}
+
+// RegisteredClient struct holds the client data returned after the registration.
+// The secret is returned only once.
+type RegisteredClient struct {
+	ClientInfo
+	Secret string `json:"client_secret,omitempty"`
+}
+
+// RegisterClientPayload is a struct holding the data of a new client.
+type RegisterClientPayload struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	// Confidential clients receive a secret. Public clients, like mobile apps, can't keep a secret.
+	Confidential bool `json:"confidential"`
+}
+
+func (p *RegisterClientPayload) ValidatePayload() *utils.ErrorResponse {
+	v := validation.New()
+	v.Required("name", p.Name).MaxLength("name", p.Name, 255)
+	v.Check("redirect_uris", len(p.RedirectURIs) > 0, utils.FieldRequired, "At least one redirect uri is required")
+	for _, uri := range p.RedirectURIs {
+		v.Required("redirect_uris", uri).URL("redirect_uris", uri)
+	}
+	return v.ErrorResponse()
+}
+
+// AuthorizationCode struct holds the data of an issued authorization code.
+type AuthorizationCode struct {
+	ClientId      string
+	UserId        int
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+}
+
+// AuthorizeQuery is a struct holding the parameters of the authorization request.
+type AuthorizeQuery struct {
+	ResponseType        string `query:"response_type" json:"response_type"`
+	ClientId            string `query:"client_id" json:"client_id"`
+	RedirectURI         string `query:"redirect_uri" json:"redirect_uri"`
+	Scope               string `query:"scope" json:"scope"`
+	State               string `query:"state" json:"state"`
+	CodeChallenge       string `query:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `query:"code_challenge_method" json:"code_challenge_method"`
+}
+
+// ValidatePayload will check that the client and the redirect uri are sent. The other parameters are
+// checked by the service, as their errors have specific codes.
+func (q *AuthorizeQuery) ValidatePayload() *utils.ErrorResponse {
+	return validation.New().
+		Required("client_id", q.ClientId).
+		Required("redirect_uri", q.RedirectURI).
+		ErrorResponse()
+}
+
+// AuthorizePayload is a struct holding the decision of the user on the consent screen.
+type AuthorizePayload struct {
+	AuthorizeQuery
+	Approve bool `json:"approve"`
+}
+
+func (p *AuthorizePayload) ValidatePayload() *utils.ErrorResponse {
+	return p.AuthorizeQuery.ValidatePayload()
+}
+
+// Consent struct holds the data shown on the consent screen.
+type Consent struct {
+	Client      ClientInfo `json:"client"`
+	Scopes      []string   `json:"scopes"`
+	RedirectURI string     `json:"redirect_uri"`
+}
+
+// AuthorizeResult struct holds the uri the user agent should be redirected to.
+type AuthorizeResult struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// OAuthTokenRequest is a struct holding the parameters of the token request.
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// OAuthTokenResponse struct holds the tokens issued to a client.
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// OAuthError is the error returned by the token endpoint as defined by RFC 6749.
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	Status      int    `json:"-"`
+}
+
+// NewOAuthError creates new instance of [OAuthError]
+func NewOAuthError(code, description string, status int) *OAuthError {
+	return &OAuthError{
+		Code:        code,
+		Description: description,
+		Status:      status,
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"reflect"
+	"server/utils"
+	"server/validation"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	v := validation.New()
+	scopes := ParseScopes(v, "scope", "tasks:read  tasks:write tasks:read")
+	if errors := v.Errors(); len(errors) != 0 {
+		t.Fatalf("Expected the scopes to be valid, got %v", errors)
+	}
+
+	expected := []string{ScopeTasksRead, ScopeTasksWrite}
+	if !reflect.DeepEqual(scopes, expected) {
+		t.Fatalf("Expected %v, got %v", expected, scopes)
+	}
+
+	tests := map[string]string{
+		"tasks:read users:write": utils.FieldInvalidEnum,
+		"  ":                     utils.FieldRequired,
+	}
+	for scope, code := range tests {
+		v = validation.New()
+		ParseScopes(v, "scope", scope)
+		if errors := v.Errors(); len(errors) != 1 || errors[0].Field != "scope" || errors[0].Code != code {
+			t.Fatalf("Expected %s error of the scope for %q, got %v", code, scope, errors)
+		}
+	}
+}
+
+func TestRegisterClientPayloadValidateRedirectURIs(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/callback":      true,
+		"http://localhost:8080/callback":    true,
+		"com.example.app://callback":        true,
+		"/callback":                         false,
+		"https://example.com/callback#frag": false,
+	}
+
+	for uri, valid := range tests {
+		payload := RegisterClientPayload{Name: "Client", RedirectURIs: []string{uri}}
+		if err := payload.ValidatePayload(); (err == nil) != valid {
+			t.Fatalf("Expected %q valid to be %v, got error %v", uri, valid, err)
+		}
+	}
+}
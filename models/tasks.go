@@ -2,8 +2,8 @@ package models
 
 import (
 	"github.com/google/uuid"
-	"net/http"
 	"server/utils"
+	"server/validation"
 )
 
 // NewTaskPayload stores tasks information.
@@ -12,22 +12,21 @@ type NewTaskPayload struct {
 	Description string  `json:"description"`
 	Priority    string  `json:"priority"`
 	Date        ISOTime `json:"date"`
+	// Done is true if the task is completed.
+	Done bool `json:"done"`
 }
 
 func (t *NewTaskPayload) ValidatePayload() *utils.ErrorResponse {
-	if t.Name == "" {
-		return utils.NewErrorResponse("Name cannot be empty", http.StatusBadRequest)
-	}
-
-	if t.Description == "" {
-		return utils.NewErrorResponse("Description cannot be empty", http.StatusBadRequest)
-	}
-
-	if t.Priority == "" {
-		return utils.NewErrorResponse("Priority cannot be empty", http.StatusBadRequest)
-	}
+	v := validation.New()
+	t.validate(v)
+	return v.ErrorResponse()
+}
 
-	return nil
+// validate will declare the rules of the task fields. Priority is checked against the priorities by the service.
+func (t *NewTaskPayload) validate(v *validation.Validator) {
+	v.Required("name", t.Name).MaxLength("name", t.Name, 100)
+	v.Required("description", t.Description)
+	v.Required("priority", t.Priority).MaxLength("priority", t.Priority, 100)
 }
 
 // TaskPayload stores task information with an id created by the server.
@@ -37,20 +36,19 @@ type TaskPayload struct {
 }
 
 func (t *TaskPayload) ValidatePayload() *utils.ErrorResponse {
-	if t.Name == "" {
-		return utils.NewErrorResponse("Name cannot be empty", http.StatusBadRequest)
-	}
-
-	if t.Description == "" {
-		return utils.NewErrorResponse("Description cannot be empty", http.StatusBadRequest)
-	}
-
-	if t.Priority == "" {
-		return utils.NewErrorResponse("Priority cannot be empty", http.StatusBadRequest)
-	}
-
-	if t.Id == uuid.Nil {
-		return utils.NewErrorResponse("Id cannot be empty", http.StatusBadRequest)
-	}
-	return nil
+	v := validation.New()
+	v.Check("id", t.Id != uuid.Nil, utils.FieldRequired, "Id is required")
+	t.validate(v)
+	return v.ErrorResponse()
+}
+
+// PriorityPayload holds a new task priority.
+type PriorityPayload struct {
+	Priority string `json:"priority"`
+}
+
+func (p *PriorityPayload) ValidatePayload() *utils.ErrorResponse {
+	v := validation.New()
+	v.Required("priority", p.Priority).MaxLength("priority", p.Priority, 100)
+	return v.ErrorResponse()
 }
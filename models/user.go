@@ -3,7 +3,19 @@ package models
 import (
 	"net/http"
 	"server/utils"
+	"server/validation"
 	"strings"
+	"time"
+)
+
+// Role is a custom type for the role of a user.
+type Role string
+
+const (
+	// RoleUser is the role of regular users.
+	RoleUser Role = "user"
+	// RoleAdmin is the role of users that can manage other users.
+	RoleAdmin Role = "admin"
 )
 
 // User struct holds user data.
@@ -12,6 +24,14 @@ type User struct {
 	Email    string
 	Username string
 	Password string
+	// EmailVerified is true if the user confirmed the email address.
+	EmailVerified bool
+	// DeleteAfter is the time after which the account will be deleted. It is nil if the deletion is not requested.
+	DeleteAfter *time.Time
+	// Role is the role of the user.
+	Role Role
+	// Disabled is true if the account is disabled by an admin.
+	Disabled bool
 }
 
 // NewUser will create instance of [User]
@@ -26,18 +46,31 @@ func NewUser(id int, email, username, password string) *User {
 
 // LoginPayload is a struct holding login information.
 type LoginPayload struct {
+	// Identifier is either the email or the username of the user.
+	Identifier string `json:"identifier"`
+	// Email is kept for clients that don't send the identifier yet. It is used only if the identifier is empty.
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
 func (p *LoginPayload) ValidatePayload() *utils.ErrorResponse {
-	if p.Email == "" || p.Password == "" {
-		return utils.NewErrorResponse("Invalid credentials", http.StatusUnauthorized)
+	if p.Identifier == "" {
+		p.Identifier = p.Email
+	}
+
+	if p.Identifier == "" || p.Password == "" {
+		return utils.NewErrorResponse(utils.CodeInvalidCredentials, "Invalid credentials", http.StatusUnauthorized)
 	}
 
 	return nil
 }
 
+// IsEmail will return true if the identifier is an email. Usernames can't contain @,
+// so the identifier can't be ambiguous.
+func (p *LoginPayload) IsEmail() bool {
+	return strings.Contains(p.Identifier, "@")
+}
+
 // RegistrationsPayload is a struct holding the user information.
 type RegistrationsPayload struct {
 	Email    string `json:"email"`
@@ -46,82 +79,113 @@ type RegistrationsPayload struct {
 }
 
 func (u *RegistrationsPayload) ValidatePayload() *utils.ErrorResponse {
-	if res := u.ValidateEmail(); res != nil {
-		return res
-	}
+	v := validation.New()
+	validateEmail(v, u.Email)
+	validateUsername(v, u.Username)
+	// The requirements of the password depend on the configured policy, so they are checked by the service.
+	v.Required("password", u.Password)
+	return v.ErrorResponse()
+}
 
-	if res := u.ValidateEmail(); res != nil {
-		return res
-	}
+// validateEmail will check if the email is a valid address that fits into the database.
+func validateEmail(v *validation.Validator, email string) {
+	v.Required("email", email).MaxLength("email", email, 255).Email("email", email)
+}
 
-	if res := u.ValidatePassword(); res != nil {
-		return res
-	}
+// MaxUsernameLength is the maximal number of characters of a username.
+const MaxUsernameLength = 255
 
-	return nil
+// validateUsername will check if the username meets the requirements of a username.
+func validateUsername(v *validation.Validator, username string) {
+	v.Required("username", username).
+		MaxLength("username", username, MaxUsernameLength).
+		Check("username", !strings.Contains(username, " "), utils.FieldInvalidUsername, "Username cannot contain spaces").
+		// The login identifier is treated as email if it contains @.
+		Check("username", !strings.Contains(username, "@"), utils.FieldInvalidUsername, "Username cannot contain @")
 }
 
-func (u *RegistrationsPayload) ValidateEmail() *utils.ErrorResponse {
-	if strings.Contains(u.Email, " ") {
-		return utils.NewErrorResponse("Email cannot contain spaces", http.StatusBadRequest)
-	}
-
-	if u.Email == "" {
-		return utils.NewErrorResponse("Email is required", http.StatusBadRequest)
-	}
+// ValidUsername will return true if the username meets the requirements of a username.
+func ValidUsername(username string) bool {
+	v := validation.New()
+	validateUsername(v, username)
+	return len(v.Errors()) == 0
+}
 
-	if !strings.Contains(u.Email, "@") {
-		return utils.NewErrorResponse("Email does not contain @", http.StatusBadRequest)
-	}
+// ForgotPasswordPayload is a struct holding the email of the user that forgot their password.
+type ForgotPasswordPayload struct {
+	Email string `json:"email"`
+}
 
-	parts := strings.Split(u.Email, "@")
-	if len(parts) != 2 {
-		return utils.NewErrorResponse("Email must contain @ only once", http.StatusBadRequest)
-	}
+func (p *ForgotPasswordPayload) ValidatePayload() *utils.ErrorResponse {
+	return validation.New().Required("email", p.Email).ErrorResponse()
+}
 
-	if parts[0] == "" {
-		return utils.NewErrorResponse("Email is missing local part (before @)", http.StatusBadRequest)
-	}
+// ResetPasswordPayload is a struct holding the reset token and the new password.
+type ResetPasswordPayload struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
 
-	if parts[1] == "" {
-		return utils.NewErrorResponse("Email is missing remote part (after @)", http.StatusBadRequest)
-	}
+func (p *ResetPasswordPayload) ValidatePayload() *utils.ErrorResponse {
+	return validation.New().
+		Required("token", p.Token).
+		Required("password", p.Password).
+		ErrorResponse()
+}
 
-	if !strings.Contains(parts[1], ".") {
-		return utils.NewErrorResponse("Domain must contain .", http.StatusBadRequest)
-	}
+// VerifyEmailPayload is a struct holding the email verification token.
+type VerifyEmailPayload struct {
+	Token string `json:"token"`
+}
 
-	return nil
+func (p *VerifyEmailPayload) ValidatePayload() *utils.ErrorResponse {
+	return validation.New().Required("token", p.Token).ErrorResponse()
 }
 
-func (u *RegistrationsPayload) ValidateUsername() *utils.ErrorResponse {
-	if strings.Contains(u.Username, " ") {
-		return utils.NewErrorResponse("Username cannot contain spaces", http.StatusBadRequest)
-	}
+// ResendVerificationPayload is a struct holding the email that should receive a new verification token.
+type ResendVerificationPayload struct {
+	Email string `json:"email"`
+}
 
-	if u.Username == "" {
-		return utils.NewErrorResponse("Username is required", http.StatusBadRequest)
-	}
+func (p *ResendVerificationPayload) ValidatePayload() *utils.ErrorResponse {
+	return validation.New().Required("email", p.Email).ErrorResponse()
+}
 
-	return nil
+// ChangePasswordPayload is a struct holding the current and the new password of the user.
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	// RevokeOtherSessions will log out every other session of the user if true.
+	RevokeOtherSessions bool `json:"revoke_other_sessions"`
 }
 
-func (u *RegistrationsPayload) ValidatePassword() *utils.ErrorResponse {
-	if strings.Contains(u.Password, " ") {
-		return utils.NewErrorResponse("Password cannot contain spaces", http.StatusBadRequest)
-	}
+func (p *ChangePasswordPayload) ValidatePayload() *utils.ErrorResponse {
+	return validation.New().
+		Required("current_password", p.CurrentPassword).
+		Required("new_password", p.NewPassword).
+		ErrorResponse()
+}
 
-	if u.Password == "" {
-		return utils.NewErrorResponse("Password is required", http.StatusBadRequest)
-	}
+// ChangeEmailPayload is a struct holding the new email and the password of the user.
+type ChangeEmailPayload struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
 
-	if len(u.Password) < 8 || len(u.Password) > 40 {
-		return utils.NewErrorResponse("Password must be between 8 and 40 characters", http.StatusBadRequest)
-	}
+func (p *ChangeEmailPayload) ValidatePayload() *utils.ErrorResponse {
+	v := validation.New()
+	validateEmail(v, p.Email)
+	v.Required("password", p.Password)
+	return v.ErrorResponse()
+}
 
-	if !strings.ContainsAny(u.Password, "-_@*&#!") {
-		return utils.NewErrorResponse("Password must contain at least one special character", http.StatusBadRequest)
-	}
+// ChangeUsernamePayload is a struct holding the new username of the user.
+type ChangeUsernamePayload struct {
+	Username string `json:"username"`
+}
 
-	return nil
+func (p *ChangeUsernamePayload) ValidatePayload() *utils.ErrorResponse {
+	v := validation.New()
+	validateUsername(v, p.Username)
+	return v.ErrorResponse()
 }
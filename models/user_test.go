@@ -0,0 +1,45 @@
+package models
+
+import (
+	"server/utils"
+	"testing"
+)
+
+func TestRegistrationsPayloadValidatePayload(t *testing.T) {
+	payload := RegistrationsPayload{Email: "user@example", Username: "user name"}
+
+	response := payload.ValidatePayload()
+	if response == nil {
+		t.Fatal("Expected the payload to be invalid")
+	}
+
+	fields := map[string]string{}
+	for _, field := range response.Fields {
+		fields[field.Field] = field.Code
+	}
+	expected := map[string]string{
+		"email":    utils.FieldInvalidEmail,
+		"username": utils.FieldInvalidUsername,
+		"password": utils.FieldRequired,
+	}
+	if len(fields) != len(expected) || len(response.Fields) != len(expected) {
+		t.Fatalf("Expected one error of every field, got %v", response.Fields)
+	}
+	for field, code := range expected {
+		if fields[field] != code {
+			t.Fatalf("Expected %s error of %s, got %q", code, field, fields[field])
+		}
+	}
+}
+
+func TestTaskPayloadValidatePayload(t *testing.T) {
+	payload := TaskPayload{NewTaskPayload: NewTaskPayload{Name: "Task", Priority: "high"}}
+
+	response := payload.ValidatePayload()
+	if response == nil || len(response.Fields) != 2 {
+		t.Fatalf("Expected errors of the id and description, got %+v", response)
+	}
+	if response.Fields[0].Field != "id" || response.Fields[1].Field != "description" {
+		t.Fatalf("Expected errors of the id and description, got %v", response.Fields)
+	}
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"server/config"
+	"time"
+)
+
+// Limiter interface counts attempts made with a key and blocks the key when there are too many.
+type Limiter interface {
+	// Check will return how long the key must wait before the next attempt.
+	// If the attempt is allowed zero is returned.
+	Check(ctx context.Context, key string) (time.Duration, error)
+
+	// Hit will record an attempt of the key and return how long the key must wait before the next attempt.
+	Hit(ctx context.Context, key string) (time.Duration, error)
+
+	// Reset will forget all attempts of the key.
+	Reset(ctx context.Context, key string) error
+
+	// DeleteExpired will delete at most limit keys whose attempts are forgotten and that are not blocked.
+	// It returns the number of deleted keys.
+	DeleteExpired(ctx context.Context, limit int) (int64, error)
+}
+
+// Policy struct holds the limits applied by a [Limiter].
+type Policy struct {
+	// FreeAttempts is how many attempts are allowed without delay.
+	FreeAttempts int
+	// BaseDelay is the delay after the first attempt over FreeAttempts.
+	// It doubles with every next attempt.
+	BaseDelay time.Duration
+	// MaxAttempts is how many attempts lock out the key.
+	MaxAttempts int
+	// Lockout is how long the key is locked out.
+	Lockout time.Duration
+	// Window is how long attempts are remembered after the last one.
+	Window time.Duration
+}
+
+// Delay will return how long the key must wait after the given number of attempts.
+func (p Policy) Delay(attempts int) time.Duration {
+	if attempts >= p.MaxAttempts {
+		return p.Lockout
+	}
+
+	if attempts <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < attempts && delay < p.Lockout; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.Lockout)
+}
+
+// NewPolicy will create [Policy] from the configuration.
+func NewPolicy(conf *config.RateLimitConfig) Policy {
+	return Policy{
+		FreeAttempts: conf.FreeAttempts,
+		BaseDelay:    conf.BaseDelay,
+		MaxAttempts:  conf.MaxAttempts,
+		Lockout:      conf.Lockout,
+		Window:       conf.Window,
+	}
+}
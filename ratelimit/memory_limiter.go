@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryEntry holds the attempts of a single key.
+type memoryEntry struct {
+	attempts     int
+	lastAttempt  time.Time
+	blockedUntil time.Time
+}
+
+// MemoryLimiter is implementation of [Limiter] that keeps the attempts in memory.
+// It doesn't share the attempts between replicas, so it is meant for tests and local development.
+type MemoryLimiter struct {
+	policy  Policy
+	entries map[string]*memoryEntry
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+func (l *MemoryLimiter) Check(_ context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok {
+		return 0, nil
+	}
+
+	return max(entry.blockedUntil.Sub(l.now()), 0), nil
+}
+
+func (l *MemoryLimiter) Hit(_ context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	entry, ok := l.entries[key]
+	if !ok || now.Sub(entry.lastAttempt) > l.policy.Window {
+		entry = &memoryEntry{}
+		l.entries[key] = entry
+	}
+
+	entry.attempts++
+	entry.lastAttempt = now
+
+	delay := l.policy.Delay(entry.attempts)
+	entry.blockedUntil = now.Add(delay)
+	return delay, nil
+}
+
+func (l *MemoryLimiter) Reset(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+	return nil
+}
+
+func (l *MemoryLimiter) DeleteExpired(_ context.Context, limit int) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var count int64
+	for key, entry := range l.entries {
+		if count >= int64(limit) {
+			break
+		}
+		if now.Sub(entry.lastAttempt) > l.policy.Window && !entry.blockedUntil.After(now) {
+			delete(l.entries, key)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func NewMemoryLimiter(policy Policy) *MemoryLimiter {
+	return &MemoryLimiter{
+		policy:  policy,
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var policy = Policy{
+	FreeAttempts: 2,
+	BaseDelay:    time.Second,
+	MaxAttempts:  5,
+	Lockout:      time.Minute,
+	Window:       time.Minute * 10,
+}
+
+// TestPolicyDelay will check if the delays grow with the attempts until the lockout.
+func TestPolicyDelay(t *testing.T) {
+	expected := []time.Duration{0, 0, 0, time.Second, time.Second * 2, time.Minute, time.Minute}
+	for attempts, delay := range expected {
+		if result := policy.Delay(attempts); result != delay {
+			t.Errorf("Delay(%d) = %v, expected %v", attempts, result, delay)
+		}
+	}
+}
+
+func TestMemoryLimiterHit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewMemoryLimiter(policy)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < policy.FreeAttempts; i++ {
+		delay, err := limiter.Hit(ctx, "key")
+		if err != nil {
+			t.Fatalf("Hit() error = %v", err)
+		}
+		if delay != 0 {
+			t.Fatalf("Expected free attempt, got delay %v", delay)
+		}
+	}
+
+	delay, _ := limiter.Hit(ctx, "key")
+	if delay != time.Second {
+		t.Fatalf("Expected delay of one second, got %v", delay)
+	}
+
+	wait, _ := limiter.Check(ctx, "key")
+	if wait != time.Second {
+		t.Fatalf("Check() = %v, expected one second", wait)
+	}
+
+	wait, _ = limiter.Check(ctx, "other")
+	if wait != 0 {
+		t.Fatalf("Other keys should not be limited, got %v", wait)
+	}
+
+	now = now.Add(time.Second)
+	wait, _ = limiter.Check(ctx, "key")
+	if wait != 0 {
+		t.Fatalf("Expected the delay to pass, got %v", wait)
+	}
+}
+
+func TestMemoryLimiterLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewMemoryLimiter(policy)
+	limiter.now = func() time.Time { return now }
+
+	var delay time.Duration
+	for i := 0; i < policy.MaxAttempts; i++ {
+		delay, _ = limiter.Hit(ctx, "key")
+	}
+	if delay != policy.Lockout {
+		t.Fatalf("Expected lockout, got delay %v", delay)
+	}
+
+	if err := limiter.Reset(ctx, "key"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+
+	wait, _ := limiter.Check(ctx, "key")
+	if wait != 0 {
+		t.Fatalf("Expected the key to be reset, got %v", wait)
+	}
+}
+
+func TestMemoryLimiterWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewMemoryLimiter(policy)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < policy.FreeAttempts; i++ {
+		_, _ = limiter.Hit(ctx, "key")
+	}
+
+	// Attempts older than the window are forgotten.
+	now = now.Add(policy.Window + time.Second)
+	delay, _ := limiter.Hit(ctx, "key")
+	if delay != 0 {
+		t.Fatalf("Expected the attempts to be forgotten, got delay %v", delay)
+	}
+}
+
+func TestMemoryLimiterDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewMemoryLimiter(policy)
+	limiter.now = func() time.Time { return now }
+
+	_, _ = limiter.Hit(ctx, "old")
+	now = now.Add(policy.Window / 2)
+	_, _ = limiter.Hit(ctx, "recent")
+	now = now.Add(policy.Window/2 + time.Second)
+
+	count, err := limiter.DeleteExpired(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || len(limiter.entries) != 1 || limiter.entries["recent"] == nil {
+		t.Fatalf("Expected only the old key to be deleted, deleted %d", count)
+	}
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PostgresLimiter is implementation of [Limiter] that keeps the attempts in postgres database,
+// so the limits are shared between all replicas of the server.
+type PostgresLimiter struct {
+	db     *sql.DB
+	policy Policy
+}
+
+func (l *PostgresLimiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	row := l.db.QueryRowContext(
+		ctx,
+		`SELECT EXTRACT(EPOCH FROM blocked_until - NOW()) FROM rate_limits
+		WHERE key = $1 AND blocked_until > NOW()`,
+		key,
+	)
+
+	var seconds float64
+	err := row.Scan(&seconds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func (l *PostgresLimiter) Hit(ctx context.Context, key string) (time.Duration, error) {
+	// The attempts are counted from the start again if the last attempt is older than the window.
+	row := l.db.QueryRowContext(
+		ctx,
+		`INSERT INTO rate_limits (key, attempts, last_attempt)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET attempts     = CASE
+		                       WHEN rate_limits.last_attempt < NOW() - MAKE_INTERVAL(secs => $2) THEN 1
+		                       ELSE rate_limits.attempts + 1
+		    END,
+		    last_attempt = NOW()
+		RETURNING attempts`,
+		key,
+		l.policy.Window.Seconds(),
+	)
+
+	var attempts int
+	if err := row.Scan(&attempts); err != nil {
+		return 0, err
+	}
+
+	delay := l.policy.Delay(attempts)
+	_, err := l.db.ExecContext(
+		ctx,
+		`UPDATE rate_limits
+		SET blocked_until = NOW() + MAKE_INTERVAL(secs => $2)
+		WHERE key = $1`,
+		key,
+		delay.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return delay, nil
+}
+
+func (l *PostgresLimiter) Reset(ctx context.Context, key string) error {
+	_, err := l.db.ExecContext(
+		ctx,
+		`DELETE FROM rate_limits
+		WHERE key = $1`,
+		key,
+	)
+
+	return err
+}
+
+func (l *PostgresLimiter) DeleteExpired(ctx context.Context, limit int) (int64, error) {
+	result, err := l.db.ExecContext(
+		ctx,
+		`DELETE FROM rate_limits
+		WHERE key IN (
+			SELECT key FROM rate_limits
+			WHERE last_attempt < NOW() - MAKE_INTERVAL(secs => $1)
+			  AND (blocked_until IS NULL OR blocked_until < NOW())
+			LIMIT $2
+		)`,
+		l.policy.Window.Seconds(),
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func NewPostgresLimiter(db *sql.DB, policy Policy) *PostgresLimiter {
+	return &PostgresLimiter{
+		db:     db,
+		policy: policy,
+	}
+}
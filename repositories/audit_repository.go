@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"server/models"
+	"server/tracing"
+	"strconv"
+	"strings"
+)
+
+// AuditRepository interface manages the append-only log of security events.
+type AuditRepository interface {
+	// AddEvent will append the event to the log.
+	AddEvent(ctx context.Context, event models.AuditEvent) error
+
+	// GetEvents will return the events matching the query, the latest first.
+	// Zero values of the query are not used as filters.
+	GetEvents(ctx context.Context, query models.AuditQuery) ([]models.AuditEvent, error)
+}
+
+// PostgresAuditRepository is implementation of [AuditRepository] using postgres database.
+type PostgresAuditRepository struct {
+	db *sql.DB
+}
+
+func (r *PostgresAuditRepository) AddEvent(ctx context.Context, event models.AuditEvent) error {
+	ctx, span := tracing.Start(ctx, "AuditRepository.AddEvent")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO audit_events (event_type, outcome, actor_id, user_id, identifier, ip, user_agent, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		event.Type,
+		event.Outcome,
+		event.ActorId,
+		event.UserId,
+		event.Identifier,
+		event.IP,
+		event.UserAgent,
+		event.Reason,
+	)
+
+	return err
+}
+
+func (r *PostgresAuditRepository) GetEvents(ctx context.Context, query models.AuditQuery) ([]models.AuditEvent, error) {
+	ctx, span := tracing.Start(ctx, "AuditRepository.GetEvents")
+	defer span.End()
+
+	var conditions []string
+	var args []any
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if query.UserId != 0 {
+		addCondition("user_id = ?", query.UserId)
+	}
+	if query.ActorId != 0 {
+		addCondition("actor_id = ?", query.ActorId)
+	}
+	if query.Type != "" {
+		addCondition("event_type = ?", query.Type)
+	}
+	if query.Outcome != "" {
+		addCondition("outcome = ?", query.Outcome)
+	}
+	if query.IP != "" {
+		addCondition("ip = ?", query.IP)
+	}
+	if !query.FromTime.IsZero() {
+		addCondition("created_at >= ?", query.FromTime)
+	}
+	if !query.ToTime.IsZero() {
+		addCondition("created_at < ?", query.ToTime)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, query.Limit, query.Offset)
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, created_at, event_type, outcome, actor_id, user_id, identifier, ip, user_agent, reason
+		FROM audit_events `+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $`+strconv.Itoa(len(args)-1)+` OFFSET $`+strconv.Itoa(len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.AuditEvent, 0, query.Limit)
+	for rows.Next() {
+		var event models.AuditEvent
+		err = rows.Scan(
+			&event.Id,
+			&event.Time,
+			&event.Type,
+			&event.Outcome,
+			&event.ActorId,
+			&event.UserId,
+			&event.Identifier,
+			&event.IP,
+			&event.UserAgent,
+			&event.Reason,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, event)
+	}
+
+	return result, rows.Err()
+}
+
+func NewPostgresAuditRepository(db *sql.DB) *PostgresAuditRepository {
+	return &PostgresAuditRepository{
+		db: db,
+	}
+}
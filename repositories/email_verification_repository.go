@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"server/tracing"
+	"time"
+)
+
+// EmailVerificationRepository interface manages the email verification tokens data.
+type EmailVerificationRepository interface {
+	// AddVerificationToken will add a new verification token by its hash.
+	AddVerificationToken(ctx context.Context, tokenHash string, exp time.Time, userId int) error
+
+	// ConsumeVerificationToken will delete the token if it is not expired and return its user id.
+	// If the token doesn't exist or is expired [sql.ErrNoRows] is returned.
+	ConsumeVerificationToken(ctx context.Context, tokenHash string) (int, error)
+
+	// DeleteUserVerificationTokens will delete all verification tokens of a user.
+	DeleteUserVerificationTokens(ctx context.Context, userId int) error
+}
+
+// PostgresEmailVerificationRepository is implementation of [EmailVerificationRepository] using postgres database.
+type PostgresEmailVerificationRepository struct {
+	db *sql.DB
+}
+
+func (r *PostgresEmailVerificationRepository) AddVerificationToken(ctx context.Context, tokenHash string, exp time.Time, userId int) error {
+	ctx, span := tracing.Start(ctx, "EmailVerificationRepository.AddVerificationToken")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO email_verification_tokens (token_hash, exp, user_id)
+		VALUES ($1, $2, $3)`,
+		tokenHash,
+		exp,
+		userId,
+	)
+
+	return err
+}
+
+func (r *PostgresEmailVerificationRepository) ConsumeVerificationToken(ctx context.Context, tokenHash string) (int, error) {
+	ctx, span := tracing.Start(ctx, "EmailVerificationRepository.ConsumeVerificationToken")
+	defer span.End()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`DELETE FROM email_verification_tokens
+		WHERE token_hash = $1 AND exp > NOW()
+		RETURNING user_id`,
+		tokenHash,
+	)
+
+	var userId int
+	err := row.Scan(&userId)
+	if err != nil {
+		return 0, err
+	}
+	return userId, nil
+}
+
+func (r *PostgresEmailVerificationRepository) DeleteUserVerificationTokens(ctx context.Context, userId int) error {
+	ctx, span := tracing.Start(ctx, "EmailVerificationRepository.DeleteUserVerificationTokens")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM email_verification_tokens
+		WHERE user_id = $1`,
+		userId,
+	)
+
+	return err
+}
+
+func NewPostgresEmailVerificationRepository(db *sql.DB) *PostgresEmailVerificationRepository {
+	return &PostgresEmailVerificationRepository{
+		db: db,
+	}
+}
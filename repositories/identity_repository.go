@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"server/tracing"
+)
+
+// IdentityRepository interface manages the accounts of users at external identity providers.
+type IdentityRepository interface {
+	// AddIdentity will link the account of the issuer to the user.
+	AddIdentity(ctx context.Context, issuer string, subject string, userId int) error
+
+	// GetIdentityUser will return the id of the user linked to the account of the issuer.
+	// If the account is not linked [sql.ErrNoRows] is returned.
+	GetIdentityUser(ctx context.Context, issuer string, subject string) (int, error)
+}
+
+// PostgresIdentityRepository is implementation of [IdentityRepository] using postgres database.
+type PostgresIdentityRepository struct {
+	db *sql.DB
+}
+
+func (r *PostgresIdentityRepository) AddIdentity(ctx context.Context, issuer string, subject string, userId int) error {
+	ctx, span := tracing.Start(ctx, "IdentityRepository.AddIdentity")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO user_identities (issuer, subject, user_id)
+		VALUES ($1, $2, $3)`,
+		issuer,
+		subject,
+		userId,
+	)
+
+	return err
+}
+
+func (r *PostgresIdentityRepository) GetIdentityUser(ctx context.Context, issuer string, subject string) (int, error) {
+	ctx, span := tracing.Start(ctx, "IdentityRepository.GetIdentityUser")
+	defer span.End()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT user_id FROM user_identities
+		WHERE issuer = $1 AND subject = $2`,
+		issuer,
+		subject,
+	)
+
+	var userId int
+	err := row.Scan(&userId)
+	if err != nil {
+		return 0, err
+	}
+	return userId, nil
+}
+
+func NewPostgresIdentityRepository(db *sql.DB) *PostgresIdentityRepository {
+	return &PostgresIdentityRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"server/models"
+	"server/tracing"
+	"strings"
+	"time"
+)
+
+// OAuthRepository interface manages the data of oauth clients and authorization codes.
+type OAuthRepository interface {
+	// AddClient will insert a new client.
+	AddClient(ctx context.Context, client models.OAuthClient) error
+
+	// GetClient will fetch the client by its id. If the client doesn't exist [sql.ErrNoRows] is returned.
+	GetClient(ctx context.Context, clientId string) (models.OAuthClient, error)
+
+	// GetUserClients will return all clients registered by the user.
+	GetUserClients(ctx context.Context, userId int) ([]models.OAuthClient, error)
+
+	// DeleteClient will delete the client of the user together with its codes and tokens.
+	// Returns true if the client was found.
+	DeleteClient(ctx context.Context, clientId string, userId int) (bool, error)
+
+	// AddAuthorizationCode will add a new authorization code by its hash.
+	AddAuthorizationCode(ctx context.Context, codeHash string, code models.AuthorizationCode, exp time.Time) error
+
+	// ConsumeAuthorizationCode will delete the code if it is not expired and return it.
+	// If the code doesn't exist or is expired [sql.ErrNoRows] is returned.
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (models.AuthorizationCode, error)
+}
+
+// PostgresOAuthRepository is implementation of [OAuthRepository] using postgres database.
+type PostgresOAuthRepository struct {
+	db *sql.DB
+}
+
+// clientColumns are the columns of oauth_clients table in the order used by [scanClient].
+const clientColumns = `id, COALESCE(secret_hash, ''), name, redirect_uris, owner_id, created_at`
+
+// scanClient will scan a row selected with [clientColumns] into [models.OAuthClient].
+func scanClient(row interface{ Scan(dest ...any) error }) (models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := row.Scan(
+		&client.Id,
+		&client.SecretHash,
+		&client.Name,
+		pq.Array(&client.RedirectURIs),
+		&client.OwnerId,
+		&client.CreatedAt,
+	)
+	return client, err
+}
+
+func (r *PostgresOAuthRepository) AddClient(ctx context.Context, client models.OAuthClient) error {
+	ctx, span := tracing.Start(ctx, "OAuthRepository.AddClient")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO oauth_clients (id, secret_hash, name, redirect_uris, owner_id, created_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)`,
+		client.Id,
+		client.SecretHash,
+		client.Name,
+		pq.Array(client.RedirectURIs),
+		client.OwnerId,
+		client.CreatedAt,
+	)
+
+	return err
+}
+
+func (r *PostgresOAuthRepository) GetClient(ctx context.Context, clientId string) (models.OAuthClient, error) {
+	ctx, span := tracing.Start(ctx, "OAuthRepository.GetClient")
+	defer span.End()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT `+clientColumns+` FROM oauth_clients
+		WHERE id = $1`,
+		clientId,
+	)
+
+	return scanClient(row)
+}
+
+func (r *PostgresOAuthRepository) GetUserClients(ctx context.Context, userId int) ([]models.OAuthClient, error) {
+	ctx, span := tracing.Start(ctx, "OAuthRepository.GetUserClients")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+clientColumns+` FROM oauth_clients
+		WHERE owner_id = $1
+		ORDER BY created_at`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.OAuthClient, 0)
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, client)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresOAuthRepository) DeleteClient(ctx context.Context, clientId string, userId int) (bool, error) {
+	ctx, span := tracing.Start(ctx, "OAuthRepository.DeleteClient")
+	defer span.End()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM oauth_clients
+		WHERE id = $1 AND owner_id = $2`,
+		clientId,
+		userId,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *PostgresOAuthRepository) AddAuthorizationCode(ctx context.Context, codeHash string, code models.AuthorizationCode, exp time.Time) error {
+	ctx, span := tracing.Start(ctx, "OAuthRepository.AddAuthorizationCode")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, exp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		codeHash,
+		code.ClientId,
+		code.UserId,
+		code.RedirectURI,
+		strings.Join(code.Scopes, " "),
+		code.CodeChallenge,
+		exp,
+	)
+
+	return err
+}
+
+func (r *PostgresOAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (models.AuthorizationCode, error) {
+	ctx, span := tracing.Start(ctx, "OAuthRepository.ConsumeAuthorizationCode")
+	defer span.End()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`DELETE FROM oauth_authorization_codes
+		WHERE code_hash = $1 AND exp > NOW()
+		RETURNING client_id, user_id, redirect_uri, scopes, code_challenge`,
+		codeHash,
+	)
+
+	var code models.AuthorizationCode
+	var scopes string
+	err := row.Scan(&code.ClientId, &code.UserId, &code.RedirectURI, &scopes, &code.CodeChallenge)
+	if err != nil {
+		return models.AuthorizationCode{}, err
+	}
+
+	code.Scopes = strings.Fields(scopes)
+	return code, nil
+}
+
+func NewPostgresOAuthRepository(db *sql.DB) *PostgresOAuthRepository {
+	return &PostgresOAuthRepository{
+		db: db,
+	}
+}
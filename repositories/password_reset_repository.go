@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"server/tracing"
+	"time"
+)
+
+// PasswordResetRepository interface manages the password reset tokens data.
+type PasswordResetRepository interface {
+	// AddResetToken will add a new reset token by its hash.
+	AddResetToken(ctx context.Context, tokenHash string, exp time.Time, userId int) error
+
+	// GetResetTokenUser will return the user id of the token without consuming it.
+	// If the token doesn't exist or is expired [sql.ErrNoRows] is returned.
+	GetResetTokenUser(ctx context.Context, tokenHash string) (int, error)
+
+	// ConsumeResetToken will delete the token if it is not expired and return its user id.
+	// If the token doesn't exist or is expired [sql.ErrNoRows] is returned.
+	ConsumeResetToken(ctx context.Context, tokenHash string) (int, error)
+
+	// DeleteUserResetTokens will delete all reset tokens of a user.
+	DeleteUserResetTokens(ctx context.Context, userId int) error
+}
+
+// PostgresPasswordResetRepository is implementation of [PasswordResetRepository] using postgres database.
+type PostgresPasswordResetRepository struct {
+	db *sql.DB
+}
+
+func (r *PostgresPasswordResetRepository) AddResetToken(ctx context.Context, tokenHash string, exp time.Time, userId int) error {
+	ctx, span := tracing.Start(ctx, "PasswordResetRepository.AddResetToken")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO password_reset_tokens (token_hash, exp, user_id)
+		VALUES ($1, $2, $3)`,
+		tokenHash,
+		exp,
+		userId,
+	)
+
+	return err
+}
+
+func (r *PostgresPasswordResetRepository) GetResetTokenUser(ctx context.Context, tokenHash string) (int, error) {
+	ctx, span := tracing.Start(ctx, "PasswordResetRepository.GetResetTokenUser")
+	defer span.End()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT user_id FROM password_reset_tokens
+		WHERE token_hash = $1 AND exp > NOW()`,
+		tokenHash,
+	)
+
+	var userId int
+	err := row.Scan(&userId)
+	if err != nil {
+		return 0, err
+	}
+	return userId, nil
+}
+
+func (r *PostgresPasswordResetRepository) ConsumeResetToken(ctx context.Context, tokenHash string) (int, error) {
+	ctx, span := tracing.Start(ctx, "PasswordResetRepository.ConsumeResetToken")
+	defer span.End()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`DELETE FROM password_reset_tokens
+		WHERE token_hash = $1 AND exp > NOW()
+		RETURNING user_id`,
+		tokenHash,
+	)
+
+	var userId int
+	err := row.Scan(&userId)
+	if err != nil {
+		return 0, err
+	}
+	return userId, nil
+}
+
+func (r *PostgresPasswordResetRepository) DeleteUserResetTokens(ctx context.Context, userId int) error {
+	ctx, span := tracing.Start(ctx, "PasswordResetRepository.DeleteUserResetTokens")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM password_reset_tokens
+		WHERE user_id = $1`,
+		userId,
+	)
+
+	return err
+}
+
+func NewPostgresPasswordResetRepository(db *sql.DB) *PostgresPasswordResetRepository {
+	return &PostgresPasswordResetRepository{
+		db: db,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"github.com/google/uuid"
 	"server/models"
+	"server/tracing"
 )
 
 // TaskRepository manages tasks data.
@@ -15,14 +16,23 @@ type TaskRepository interface {
 	// CheckPriority will check if the task priority is in the database.
 	CheckPriority(ctx context.Context, priority string) (bool, error)
 
+	// GetPriorities will return all task priorities ordered by name.
+	GetPriorities(ctx context.Context) ([]string, error)
+
+	// AddPriority will add a new task priority. Returns false if the priority already exists.
+	AddPriority(ctx context.Context, priority string) (bool, error)
+
 	// AddTask will add new task.
 	AddTask(ctx context.Context, taskPayload *models.TaskPayload, userId int) error
 
-	// UpdateTask will update an existing task. Returns true if the task was updated.
-	UpdateTask(ctx context.Context, task *models.TaskPayload) (bool, error)
+	// UpdateTask will update an existing task of the user. Returns true if the task was updated.
+	UpdateTask(ctx context.Context, task *models.TaskPayload, userId int) (bool, error)
 
-	// DeleteTask will delete an existing task. Return true  if the task was deleted.
-	DeleteTask(ctx context.Context, taskId uuid.UUID) (bool, error)
+	// DeleteTask will delete an existing task of the user. Return true  if the task was deleted.
+	DeleteTask(ctx context.Context, taskId uuid.UUID, userId int) (bool, error)
+
+	// CountTasksByPriority will count the tasks of a user grouped by priority.
+	CountTasksByPriority(ctx context.Context, userId int) (map[string]int, error)
 }
 
 // PostgresTaskRepository is default implementation of [TaskRepository] using postgres database.
@@ -45,6 +55,9 @@ func (r *PostgresTaskRepository) countTasks(ctx context.Context, userId int) (in
 }
 
 func (r *PostgresTaskRepository) GetTasks(ctx context.Context, userId int) ([]models.TaskPayload, error) {
+	ctx, span := tracing.Start(ctx, "TaskRepository.GetTasks")
+	defer span.End()
+
 	count, err := r.countTasks(ctx, userId)
 	if err != nil {
 		return nil, err
@@ -53,7 +66,7 @@ func (r *PostgresTaskRepository) GetTasks(ctx context.Context, userId int) ([]mo
 	result := make([]models.TaskPayload, 0, count)
 
 	rows, err := r.db.Query(
-		`SELECT id, name, description, priority, date FROM tasks
+		`SELECT id, name, description, priority, date, done FROM tasks
                 WHERE user_id = $1`,
 		userId)
 
@@ -63,7 +76,7 @@ func (r *PostgresTaskRepository) GetTasks(ctx context.Context, userId int) ([]mo
 
 	for rows.Next() {
 		var task models.TaskPayload
-		err = rows.Scan(&task.Id, &task.Name, &task.Description, &task.Priority, &task.Date)
+		err = rows.Scan(&task.Id, &task.Name, &task.Description, &task.Priority, &task.Date, &task.Done)
 		if err != nil {
 			return nil, err
 		}
@@ -74,6 +87,9 @@ func (r *PostgresTaskRepository) GetTasks(ctx context.Context, userId int) ([]mo
 }
 
 func (r *PostgresTaskRepository) CheckPriority(ctx context.Context, priority string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TaskRepository.CheckPriority")
+	defer span.End()
+
 	row := r.db.QueryRowContext(
 		ctx,
 		`SELECT COUNT(*) FROM priorities
@@ -86,37 +102,90 @@ func (r *PostgresTaskRepository) CheckPriority(ctx context.Context, priority str
 	return count > 0, err
 }
 
+func (r *PostgresTaskRepository) GetPriorities(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "TaskRepository.GetPriorities")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT priority FROM priorities ORDER BY priority`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var priority string
+		if err = rows.Scan(&priority); err != nil {
+			return nil, err
+		}
+		result = append(result, priority)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresTaskRepository) AddPriority(ctx context.Context, priority string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TaskRepository.AddPriority")
+	defer span.End()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO priorities (priority) VALUES ($1)
+		ON CONFLICT (priority) DO NOTHING`,
+		priority,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
 func (r *PostgresTaskRepository) AddTask(ctx context.Context, task *models.TaskPayload, userId int) error {
+	ctx, span := tracing.Start(ctx, "TaskRepository.AddTask")
+	defer span.End()
+
 	_, err := r.db.ExecContext(
 		ctx,
-		`INSERT INTO tasks (id, name, description, priority, date, user_id)
-			VALUES ($1, $2, $3, $4, $5, $6)
+		`INSERT INTO tasks (id, name, description, priority, date, done, user_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
 		`,
 		task.Id,
 		task.Name,
 		task.Description,
 		task.Priority,
 		&task.Date,
+		task.Done,
 		userId,
 	)
 
 	return err
 }
 
-func (r *PostgresTaskRepository) UpdateTask(ctx context.Context, task *models.TaskPayload) (bool, error) {
+func (r *PostgresTaskRepository) UpdateTask(ctx context.Context, task *models.TaskPayload, userId int) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TaskRepository.UpdateTask")
+	defer span.End()
+
 	result, err := r.db.ExecContext(
 		ctx,
 		`UPDATE tasks
 		SET name        = $1,
  		description = $2,
     	priority    = $3,
-    	date        = $4
-		WHERE id = $5`,
+    	date        = $4,
+    	done        = $5
+		WHERE id = $6 AND user_id = $7`,
 		task.Name,
 		task.Description,
 		task.Priority,
 		&task.Date,
+		task.Done,
 		task.Id,
+		userId,
 	)
 
 	if err != nil {
@@ -131,12 +200,16 @@ func (r *PostgresTaskRepository) UpdateTask(ctx context.Context, task *models.Ta
 	return rows > 0, nil
 }
 
-func (r *PostgresTaskRepository) DeleteTask(ctx context.Context, taskId uuid.UUID) (bool, error) {
+func (r *PostgresTaskRepository) DeleteTask(ctx context.Context, taskId uuid.UUID, userId int) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TaskRepository.DeleteTask")
+	defer span.End()
+
 	result, err := r.db.ExecContext(
 		ctx,
 		`DELETE FROM tasks 
-       WHERE id = $1`,
+       WHERE id = $1 AND user_id = $2`,
 		taskId,
+		userId,
 	)
 
 	if err != nil {
@@ -150,6 +223,35 @@ func (r *PostgresTaskRepository) DeleteTask(ctx context.Context, taskId uuid.UUI
 	return rows > 0, nil
 }
 
+func (r *PostgresTaskRepository) CountTasksByPriority(ctx context.Context, userId int) (map[string]int, error) {
+	ctx, span := tracing.Start(ctx, "TaskRepository.CountTasksByPriority")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT priority, COUNT(*) FROM tasks
+		WHERE user_id = $1
+		GROUP BY priority`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]int)
+	for rows.Next() {
+		var priority string
+		var count int
+		if err = rows.Scan(&priority, &count); err != nil {
+			return nil, err
+		}
+		result[priority] = count
+	}
+
+	return result, rows.Err()
+}
+
 func NewPostgresTaskRepository(db *sql.DB) *PostgresTaskRepository {
 	return &PostgresTaskRepository{db}
 }
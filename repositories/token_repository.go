@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"github.com/google/uuid"
+	"server/models"
+	"server/tracing"
+	"strings"
 	"time"
 )
 
@@ -16,7 +19,27 @@ type TokenRepository interface {
 	DeleteToken(ctx context.Context, tokenId uuid.UUID) error
 
 	// CheckToken will search the token id the database and return its subject - The user id.
+	// Tokens issued to oauth clients are not matched.
 	CheckToken(ctx context.Context, tokenId uuid.UUID) (int, error)
+
+	// AddClientToken will add a new token issued to the oauth client with the granted scopes.
+	AddClientToken(ctx context.Context, tokenId uuid.UUID, exp time.Time, userId int, clientId string, scopes []string) error
+
+	// CheckClientToken will search the token of the oauth client and return its user id and granted scopes.
+	// If the token doesn't exist or belongs to another client [sql.ErrNoRows] is returned.
+	CheckClientToken(ctx context.Context, tokenId uuid.UUID, clientId string) (int, []string, error)
+
+	// DeleteUserTokens will delete all tokens of a user.
+	DeleteUserTokens(ctx context.Context, userId int) error
+
+	// DeleteUserTokensExcept will delete all tokens of a user except the one with the given id.
+	DeleteUserTokensExcept(ctx context.Context, userId int, tokenId uuid.UUID) error
+
+	// GetUserTokens will return all tokens of a user as sessions.
+	GetUserTokens(ctx context.Context, userId int) ([]models.Session, error)
+
+	// DeleteExpiredTokens will delete at most limit expired tokens. It returns the number of deleted tokens.
+	DeleteExpiredTokens(ctx context.Context, limit int) (int64, error)
 }
 
 type PostgresTokenRepository struct {
@@ -24,6 +47,9 @@ type PostgresTokenRepository struct {
 }
 
 func (r *PostgresTokenRepository) AddToken(ctx context.Context, tokenId uuid.UUID, exp time.Time, userId int) error {
+	ctx, span := tracing.Start(ctx, "TokenRepository.AddToken")
+	defer span.End()
+
 	_, err := r.db.ExecContext(
 		ctx,
 		`INSERT INTO tokens (id, exp, user_id)
@@ -37,6 +63,9 @@ func (r *PostgresTokenRepository) AddToken(ctx context.Context, tokenId uuid.UUI
 }
 
 func (r *PostgresTokenRepository) DeleteToken(ctx context.Context, tokenId uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "TokenRepository.DeleteToken")
+	defer span.End()
+
 	_, err := r.db.ExecContext(
 		ctx,
 		`DELETE FROM tokens
@@ -48,10 +77,13 @@ func (r *PostgresTokenRepository) DeleteToken(ctx context.Context, tokenId uuid.
 }
 
 func (r *PostgresTokenRepository) CheckToken(ctx context.Context, tokenId uuid.UUID) (int, error) {
+	ctx, span := tracing.Start(ctx, "TokenRepository.CheckToken")
+	defer span.End()
+
 	row := r.db.QueryRowContext(
 		ctx,
 		`SELECT user_id FROM tokens
-               WHERE id = $1`,
+               WHERE id = $1 AND client_id IS NULL`,
 		tokenId,
 	)
 
@@ -63,6 +95,119 @@ func (r *PostgresTokenRepository) CheckToken(ctx context.Context, tokenId uuid.U
 	return userId, nil
 }
 
+func (r *PostgresTokenRepository) AddClientToken(ctx context.Context, tokenId uuid.UUID, exp time.Time, userId int, clientId string, scopes []string) error {
+	ctx, span := tracing.Start(ctx, "TokenRepository.AddClientToken")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO tokens (id, exp, user_id, client_id, scopes)
+		VALUES ($1, $2, $3, $4, $5)`,
+		tokenId,
+		exp,
+		userId,
+		clientId,
+		strings.Join(scopes, " "),
+	)
+
+	return err
+}
+
+func (r *PostgresTokenRepository) CheckClientToken(ctx context.Context, tokenId uuid.UUID, clientId string) (int, []string, error) {
+	ctx, span := tracing.Start(ctx, "TokenRepository.CheckClientToken")
+	defer span.End()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT user_id, scopes FROM tokens
+		WHERE id = $1 AND client_id = $2`,
+		tokenId,
+		clientId,
+	)
+
+	var userId int
+	var scopes string
+	err := row.Scan(&userId, &scopes)
+	if err != nil {
+		return 0, nil, err
+	}
+	return userId, strings.Fields(scopes), nil
+}
+
+func (r *PostgresTokenRepository) DeleteUserTokens(ctx context.Context, userId int) error {
+	ctx, span := tracing.Start(ctx, "TokenRepository.DeleteUserTokens")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM tokens
+       WHERE user_id = $1`,
+		userId,
+	)
+
+	return err
+}
+
+func (r *PostgresTokenRepository) DeleteUserTokensExcept(ctx context.Context, userId int, tokenId uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "TokenRepository.DeleteUserTokensExcept")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM tokens
+       WHERE user_id = $1 AND id <> $2`,
+		userId,
+		tokenId,
+	)
+
+	return err
+}
+
+func (r *PostgresTokenRepository) GetUserTokens(ctx context.Context, userId int) ([]models.Session, error) {
+	ctx, span := tracing.Start(ctx, "TokenRepository.GetUserTokens")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, exp FROM tokens
+       WHERE user_id = $1`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.Session, 0)
+	for rows.Next() {
+		var session models.Session
+		err = rows.Scan(&session.Id, &session.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, session)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresTokenRepository) DeleteExpiredTokens(ctx context.Context, limit int) (int64, error) {
+	ctx, span := tracing.Start(ctx, "TokenRepository.DeleteExpiredTokens")
+	defer span.End()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM tokens
+		WHERE id IN (SELECT id FROM tokens WHERE exp < NOW() LIMIT $1)`,
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 func NewPostgresTokenRepository(db *sql.DB) *PostgresTokenRepository {
 	return &PostgresTokenRepository{
 		db: db,
@@ -3,23 +3,99 @@ package repositories
 import (
 	"context"
 	"database/sql"
-	_ "github.com/lib/pq"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
 	"server/models"
+	"server/tracing"
+	"strings"
+	"time"
 )
 
+// ErrDuplicate is returned when the value is already used by another user. It happens when the value
+// is taken concurrently after the check for its uniqueness.
+var ErrDuplicate = errors.New("value is already in use")
+
+var (
+	// ErrDuplicateEmail is [ErrDuplicate] of the email.
+	ErrDuplicateEmail = fmt.Errorf("email: %w", ErrDuplicate)
+	// ErrDuplicateUsername is [ErrDuplicate] of the username.
+	ErrDuplicateUsername = fmt.Errorf("username: %w", ErrDuplicate)
+)
+
+// duplicateError will return [ErrDuplicate] if the error is the unique violation of postgres.
+// The value is known from the violated constraint, like users_email_key or users_username_lower_key.
+func duplicateError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(pqErr.Constraint, "users_email"):
+		return ErrDuplicateEmail
+	case strings.HasPrefix(pqErr.Constraint, "users_username"):
+		return ErrDuplicateUsername
+	default:
+		return ErrDuplicate
+	}
+}
+
 // UserRepository interface manages the data of users.
 type UserRepository interface {
 	// CheckIfEmailExists will return true if the email is in use otherwise false.
+	// The email is compared case-insensitively.
 	CheckIfEmailExists(ctx context.Context, email string) (bool, error)
 
-	// CheckIfUsernameExists will return true if the email is in use otherwise false.
+	// CheckIfUsernameExists will return true if the username is in use otherwise false.
+	// The username is compared case-insensitively.
 	CheckIfUsernameExists(ctx context.Context, username string) (bool, error)
 
-	// AddUser will insert a new user.
-	AddUser(ctx context.Context, email string, username string, password string) error
+	// AddUser will insert a new user and return its id.
+	// If the email or the username is used by another user [ErrDuplicateEmail] or [ErrDuplicateUsername] is returned.
+	AddUser(ctx context.Context, email string, username string, password string) (int, error)
 
-	// GetUserByEmail will fetch user by the email. If the user email doesn't exit error is returned.s
+	// GetUserByEmail will fetch user by the email compared case-insensitively.
+	// If the user email doesn't exist [sql.ErrNoRows] is returned.
 	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+
+	// GetUserByUsername will fetch user by the username compared case-insensitively.
+	// If the username doesn't exist [sql.ErrNoRows] is returned.
+	GetUserByUsername(ctx context.Context, username string) (models.User, error)
+
+	// GetUserById will fetch user by the id. If the user doesn't exist [sql.ErrNoRows] is returned.
+	GetUserById(ctx context.Context, userId int) (models.User, error)
+
+	// MarkEmailVerified will mark the email of the user as verified.
+	MarkEmailVerified(ctx context.Context, userId int) error
+
+	// UpdateEmail will change the email of the user and mark it as not verified, unless only its case changed.
+	// If the email is used by another user [ErrDuplicate] is returned.
+	UpdateEmail(ctx context.Context, userId int, email string) error
+
+	// UpdateUsername will change the username of the user.
+	// If the username is used by another user [ErrDuplicate] is returned.
+	UpdateUsername(ctx context.Context, userId int, username string) error
+
+	// ScheduleDeletion will mark the user to be deleted after the given time.
+	ScheduleDeletion(ctx context.Context, userId int, deleteAfter time.Time) error
+
+	// CancelDeletion will remove the scheduled deletion of the user.
+	CancelDeletion(ctx context.Context, userId int) error
+
+	// DeleteScheduledUsers will delete all users whose deletion time has passed
+	// together with their data. The identifier, ip and user agent of their audit events
+	// are cleared, the events themselves are kept. It returns the number of deleted users.
+	DeleteScheduledUsers(ctx context.Context) (int64, error)
+
+	// SearchUsers will return users whose email or username contains the query ordered by id.
+	SearchUsers(ctx context.Context, query string, limit int, offset int) ([]models.User, error)
+
+	// SetDisabled will enable or disable the user. Returns true if the user was found.
+	SetDisabled(ctx context.Context, userId int, disabled bool) (bool, error)
+
+	// UpdatePassword will replace the password hash of the user.
+	UpdatePassword(ctx context.Context, userId int, password string) error
 }
 
 // PostgresUserRepository struct manages data using connection to postgres database.
@@ -28,9 +104,12 @@ type PostgresUserRepository struct {
 }
 
 func (r *PostgresUserRepository) CheckIfEmailExists(ctx context.Context, email string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.CheckIfEmailExists")
+	defer span.End()
+
 	row := r.db.QueryRowContext(ctx,
 		`SELECT COUNT(*) FROM users 
-		WHERE email = $1`,
+		WHERE LOWER(email) = LOWER($1)`,
 		email,
 	)
 
@@ -42,9 +121,12 @@ func (r *PostgresUserRepository) CheckIfEmailExists(ctx context.Context, email s
 }
 
 func (r *PostgresUserRepository) CheckIfUsernameExists(ctx context.Context, username string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.CheckIfUsernameExists")
+	defer span.End()
+
 	row := r.db.QueryRowContext(ctx,
 		`SELECT COUNT(*) FROM users 
-		WHERE username = $1`,
+		WHERE LOWER(username) = LOWER($1)`,
 		username,
 	)
 
@@ -55,27 +137,242 @@ func (r *PostgresUserRepository) CheckIfUsernameExists(ctx context.Context, user
 	return count > 0, nil
 }
 
-func (r *PostgresUserRepository) AddUser(ctx context.Context, email string, username string, password string) error {
-	_, err := r.db.ExecContext(ctx,
+func (r *PostgresUserRepository) AddUser(ctx context.Context, email string, username string, password string) (int, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.AddUser")
+	defer span.End()
+
+	row := r.db.QueryRowContext(ctx,
 		`INSERT INTO users (email, username, password) 
-		VALUES ($1, $2, $3)`,
+		VALUES ($1, $2, $3)
+		RETURNING id`,
 		email,
 		username,
 		password,
 	)
-	return err
+
+	var id int
+	err := row.Scan(&id)
+	return id, duplicateError(err)
+}
+
+// userColumns are the columns of users table in the order used by [scanUser].
+const userColumns = `id, email, username, password, email_verified, delete_after, role, disabled`
+
+// scanUser will scan a row selected with [userColumns] into [models.User].
+func scanUser(row interface{ Scan(dest ...any) error }) (models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.Id,
+		&user.Email,
+		&user.Username,
+		&user.Password,
+		&user.EmailVerified,
+		&user.DeleteAfter,
+		&user.Role,
+		&user.Disabled,
+	)
+	return user, err
 }
 
 func (r *PostgresUserRepository) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetUserByEmail")
+	defer span.End()
+
 	row := r.db.QueryRowContext(ctx,
-		`SELECT * FROM users
-		WHERE email = $1`,
+		`SELECT `+userColumns+` FROM users
+		WHERE LOWER(email) = LOWER($1)`,
 		email,
 	)
 
-	var user models.User
-	err := row.Scan(&user.Id, &user.Email, &user.Username, &user.Password)
-	return user, err
+	return scanUser(row)
+}
+
+func (r *PostgresUserRepository) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetUserByUsername")
+	defer span.End()
+
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` FROM users
+		WHERE LOWER(username) = LOWER($1)`,
+		username,
+	)
+
+	return scanUser(row)
+}
+
+func (r *PostgresUserRepository) GetUserById(ctx context.Context, userId int) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetUserById")
+	defer span.End()
+
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` FROM users
+		WHERE id = $1`,
+		userId,
+	)
+
+	return scanUser(row)
+}
+
+func (r *PostgresUserRepository) MarkEmailVerified(ctx context.Context, userId int) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.MarkEmailVerified")
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users
+		SET email_verified = TRUE
+		WHERE id = $1`,
+		userId,
+	)
+	return err
+}
+
+func (r *PostgresUserRepository) UpdatePassword(ctx context.Context, userId int, password string) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.UpdatePassword")
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users
+		SET password = $1
+		WHERE id = $2`,
+		password,
+		userId,
+	)
+	return err
+}
+
+func (r *PostgresUserRepository) UpdateEmail(ctx context.Context, userId int, email string) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.UpdateEmail")
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users
+		SET email = $1, email_verified = email_verified AND LOWER(email) = LOWER($1)
+		WHERE id = $2`,
+		email,
+		userId,
+	)
+	return duplicateError(err)
+}
+
+func (r *PostgresUserRepository) UpdateUsername(ctx context.Context, userId int, username string) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.UpdateUsername")
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users
+		SET username = $1
+		WHERE id = $2`,
+		username,
+		userId,
+	)
+	return duplicateError(err)
+}
+
+func (r *PostgresUserRepository) ScheduleDeletion(ctx context.Context, userId int, deleteAfter time.Time) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.ScheduleDeletion")
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users
+		SET delete_after = $1
+		WHERE id = $2`,
+		deleteAfter,
+		userId,
+	)
+	return err
+}
+
+func (r *PostgresUserRepository) CancelDeletion(ctx context.Context, userId int) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.CancelDeletion")
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users
+		SET delete_after = NULL
+		WHERE id = $1`,
+		userId,
+	)
+	return err
+}
+
+func (r *PostgresUserRepository) DeleteScheduledUsers(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.DeleteScheduledUsers")
+	defer span.End()
+
+	// Both statements run in one query, so the users are never deleted without anonymizing their events.
+	row := r.db.QueryRowContext(ctx,
+		`WITH deleted AS (
+			DELETE FROM users
+			WHERE delete_after <= NOW()
+			RETURNING id
+		), anonymized AS (
+			UPDATE audit_events
+			SET identifier = '', ip = '', user_agent = ''
+			WHERE user_id IN (SELECT id FROM deleted) OR actor_id IN (SELECT id FROM deleted)
+		)
+		SELECT COUNT(*) FROM deleted`,
+	)
+
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+func (r *PostgresUserRepository) SearchUsers(ctx context.Context, query string, limit int, offset int) ([]models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.SearchUsers")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+userColumns+` FROM users
+		WHERE email ILIKE '%' || $1 || '%' OR username ILIKE '%' || $1 || '%'
+		ORDER BY id
+		LIMIT $2 OFFSET $3`,
+		escapeLike(query),
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.User, 0, limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, user)
+	}
+
+	return result, rows.Err()
+}
+
+// escapeLike will escape the special characters of LIKE patterns, so the value is matched literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func (r *PostgresUserRepository) SetDisabled(ctx context.Context, userId int, disabled bool) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.SetDisabled")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users
+		SET disabled = $1
+		WHERE id = $2`,
+		disabled,
+		userId,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
 }
 
 func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
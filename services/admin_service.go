@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"server/audit"
+	"server/auth/tokens"
+	"server/models"
+	"server/repositories"
+	"server/tracing"
+	"server/utils"
+)
+
+// AdminService interface manage the business logic for administration of users.
+type AdminService interface {
+	// SearchUsers will return users whose email or username contains the query.
+	SearchUsers(ctx context.Context, query models.UserSearchQuery) ([]models.AdminUser, *utils.ErrorResponse)
+
+	// DisableUser will disable the account of the user and revoke all their sessions.
+	// Admins can't disable their own account.
+	DisableUser(ctx context.Context, token tokens.Token, userId int) *utils.ErrorResponse
+
+	// EnableUser will enable a disabled account of the user.
+	EnableUser(ctx context.Context, token tokens.Token, userId int) *utils.ErrorResponse
+
+	// LogoutUser will revoke all sessions of the user.
+	LogoutUser(ctx context.Context, token tokens.Token, userId int) *utils.ErrorResponse
+
+	// SearchAuditEvents will return the security events matching the query.
+	SearchAuditEvents(ctx context.Context, query models.AuditQuery) ([]models.AuditEvent, *utils.ErrorResponse)
+
+	// GetTaskCounts will return the number of tasks of the user.
+	GetTaskCounts(ctx context.Context, userId int) (*models.TaskCounts, *utils.ErrorResponse)
+}
+
+// DefaultAdminService is the default implementation of [AdminService].
+type DefaultAdminService struct {
+	userRepository  repositories.UserRepository
+	tokenRepository repositories.TokenRepository
+	taskRepository  repositories.TaskRepository
+	auditRepository repositories.AuditRepository
+	recorder        audit.Recorder
+}
+
+// record will record the security event caused by the admin on the account of the user.
+// The event is failed if the error response is not nil.
+func (s *DefaultAdminService) record(ctx context.Context, eventType models.AuditEventType, token tokens.Token, userId int, errorResponse *utils.ErrorResponse) {
+	event := models.NewAuditEvent(eventType, userId)
+	actorId := tokenUserId(token)
+	event.ActorId = &actorId
+
+	if errorResponse != nil {
+		event.Outcome = models.AuditFailure
+		event.Reason = errorResponse.Message
+	}
+
+	s.recorder.Record(ctx, event)
+}
+
+func (s *DefaultAdminService) SearchUsers(ctx context.Context, query models.UserSearchQuery) ([]models.AdminUser, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "AdminService.SearchUsers")
+	defer span.End()
+
+	users, err := s.userRepository.SearchUsers(ctx, query.Query, query.Limit, query.Offset)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	result := make([]models.AdminUser, 0, len(users))
+	for _, user := range users {
+		result = append(result, *models.NewAdminUser(user))
+	}
+
+	return result, nil
+}
+
+func (s *DefaultAdminService) DisableUser(ctx context.Context, token tokens.Token, userId int) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "AdminService.DisableUser")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.AuditAccountDisabled, token, userId, errorResponse)
+	}()
+
+	if tokenUserId(token) == userId {
+		return utils.NewErrorResponse(utils.CodeCannotDisableSelf, "Admins can't disable their own account", http.StatusBadRequest)
+	}
+
+	result, err := s.userRepository.SetDisabled(ctx, userId, true)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+	if !result {
+		return utils.NewErrorResponse(utils.CodeUserNotFound, "User not found", http.StatusNotFound)
+	}
+
+	err = s.tokenRepository.DeleteUserTokens(ctx, userId)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	return nil
+}
+
+func (s *DefaultAdminService) EnableUser(ctx context.Context, token tokens.Token, userId int) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "AdminService.EnableUser")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.AuditAccountEnabled, token, userId, errorResponse)
+	}()
+
+	result, err := s.userRepository.SetDisabled(ctx, userId, false)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+	if !result {
+		return utils.NewErrorResponse(utils.CodeUserNotFound, "User not found", http.StatusNotFound)
+	}
+
+	return nil
+}
+
+func (s *DefaultAdminService) LogoutUser(ctx context.Context, token tokens.Token, userId int) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "AdminService.LogoutUser")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.AuditTokensRevoked, token, userId, errorResponse)
+	}()
+
+	err := s.tokenRepository.DeleteUserTokens(ctx, userId)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	return nil
+}
+
+func (s *DefaultAdminService) GetTaskCounts(ctx context.Context, userId int) (*models.TaskCounts, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetTaskCounts")
+	defer span.End()
+
+	counts, err := s.taskRepository.CountTasksByPriority(ctx, userId)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	result := &models.TaskCounts{ByPriority: counts}
+	for _, count := range counts {
+		result.Total += count
+	}
+
+	return result, nil
+}
+
+func (s *DefaultAdminService) SearchAuditEvents(ctx context.Context, query models.AuditQuery) ([]models.AuditEvent, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "AdminService.SearchAuditEvents")
+	defer span.End()
+
+	events, err := s.auditRepository.GetEvents(ctx, query)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	return events, nil
+}
+
+func NewDefaultAdminService(
+	userRepository repositories.UserRepository,
+	tokenRepository repositories.TokenRepository,
+	taskRepository repositories.TaskRepository,
+	auditRepository repositories.AuditRepository,
+	recorder audit.Recorder,
+) *DefaultAdminService {
+	return &DefaultAdminService{
+		userRepository:  userRepository,
+		tokenRepository: tokenRepository,
+		taskRepository:  taskRepository,
+		auditRepository: auditRepository,
+		recorder:        recorder,
+	}
+}
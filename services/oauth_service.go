@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"server/auth/tokens"
+	"server/config"
+	"server/logging"
+	"server/models"
+	"server/repositories"
+	"server/tracing"
+	"server/utils"
+	"server/validation"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OAuthService interface manage the business logic of the oauth authorization server.
+type OAuthService interface {
+	// RegisterClient will register a new client owned by the user.
+	// The secret of a confidential client is returned only once.
+	RegisterClient(ctx context.Context, token tokens.Token, payload models.RegisterClientPayload) (*models.RegisteredClient, *utils.ErrorResponse)
+
+	// GetClients will return all clients registered by the user.
+	GetClients(ctx context.Context, token tokens.Token) ([]models.ClientInfo, *utils.ErrorResponse)
+
+	// DeleteClient will delete the client of the user and revoke all tokens issued to it.
+	DeleteClient(ctx context.Context, token tokens.Token, clientId string) *utils.ErrorResponse
+
+	// GetConsent will validate the authorization request and return the data shown on the consent screen.
+	GetConsent(ctx context.Context, token tokens.Token, query models.AuthorizeQuery) (*models.Consent, *utils.ErrorResponse)
+
+	// Authorize will issue an authorization code if the user approved the request. The result holds
+	// the redirect uri of the client with either the code or the access_denied error.
+	Authorize(ctx context.Context, token tokens.Token, payload models.AuthorizePayload) (*models.AuthorizeResult, *utils.ErrorResponse)
+
+	// Exchange will issue tokens for the authorization code or the refresh token of the client.
+	Exchange(ctx context.Context, request models.OAuthTokenRequest) (*models.OAuthTokenResponse, *models.OAuthError)
+}
+
+const (
+	// authorizationCodeExpiration is how long the authorization code can be exchanged.
+	authorizationCodeExpiration = time.Minute * 10
+	// clientAccessTokenExpiration is how long the access token of a client is valid.
+	clientAccessTokenExpiration = time.Minute * 10
+	// clientRefreshTokenExpiration is how long the refresh token of a client is valid.
+	clientRefreshTokenExpiration = time.Hour * 24 * 30
+)
+
+// DefaultOAuthService is the default implementation of [OAuthService].
+type DefaultOAuthService struct {
+	oauthRepository  repositories.OAuthRepository
+	tokenRepository  repositories.TokenRepository
+	userRepository   repositories.UserRepository
+	authenticator    *tokens.JWTAuthenticator
+	unverifiedPolicy config.UnverifiedPolicy
+}
+
+func (s *DefaultOAuthService) RegisterClient(ctx context.Context, token tokens.Token, payload models.RegisterClientPayload) (*models.RegisteredClient, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "OAuthService.RegisterClient")
+	defer span.End()
+
+	userId, err := strconv.Atoi(token.Subject)
+	if err != nil {
+		return nil, utils.InvalidTokenErrorResponse()
+	}
+
+	client := models.OAuthClient{
+		Id:           uuid.New().String(),
+		Name:         payload.Name,
+		RedirectURIs: payload.RedirectURIs,
+		OwnerId:      userId,
+		CreatedAt:    time.Now(),
+	}
+
+	var secret string
+	if payload.Confidential {
+		secret, client.SecretHash, err = tokens.NewOpaqueToken()
+		if err != nil {
+			return nil, utils.InternalError(ctx, err)
+		}
+	}
+
+	err = s.oauthRepository.AddClient(ctx, client)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	return &models.RegisteredClient{
+		ClientInfo: *models.NewClientInfo(client),
+		Secret:     secret,
+	}, nil
+}
+
+func (s *DefaultOAuthService) GetClients(ctx context.Context, token tokens.Token) ([]models.ClientInfo, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "OAuthService.GetClients")
+	defer span.End()
+
+	userId, err := strconv.Atoi(token.Subject)
+	if err != nil {
+		return nil, utils.InvalidTokenErrorResponse()
+	}
+
+	clients, err := s.oauthRepository.GetUserClients(ctx, userId)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	result := make([]models.ClientInfo, 0, len(clients))
+	for _, client := range clients {
+		result = append(result, *models.NewClientInfo(client))
+	}
+
+	return result, nil
+}
+
+func (s *DefaultOAuthService) DeleteClient(ctx context.Context, token tokens.Token, clientId string) *utils.ErrorResponse {
+	ctx, span := tracing.Start(ctx, "OAuthService.DeleteClient")
+	defer span.End()
+
+	userId, err := strconv.Atoi(token.Subject)
+	if err != nil {
+		return utils.InvalidTokenErrorResponse()
+	}
+
+	result, err := s.oauthRepository.DeleteClient(ctx, clientId, userId)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+	if !result {
+		return utils.NewErrorResponse(utils.CodeClientNotFound, "Client not found", http.StatusNotFound)
+	}
+
+	return nil
+}
+
+// validateAuthorization will check the authorization request and return the client and the requested scopes.
+func (s *DefaultOAuthService) validateAuthorization(ctx context.Context, query models.AuthorizeQuery) (*models.OAuthClient, []string, *utils.ErrorResponse) {
+	client, err := s.oauthRepository.GetClient(ctx, query.ClientId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, utils.NewErrorResponse(utils.CodeUnknownClient, "Unknown client", http.StatusBadRequest)
+	} else if err != nil {
+		return nil, nil, utils.InternalError(ctx, err)
+	}
+
+	// The user must not be redirected to an unregistered uri, so this is checked first.
+	if !client.HasRedirectURI(query.RedirectURI) {
+		return nil, nil, utils.NewErrorResponse(utils.CodeInvalidRedirectURI, "Redirect uri is not registered for the client", http.StatusBadRequest)
+	}
+
+	if query.ResponseType != "code" {
+		return nil, nil, utils.NewErrorResponse(utils.CodeUnsupportedResponse, "Response type must be code", http.StatusBadRequest)
+	}
+
+	v := validation.New()
+	scopes := models.ParseScopes(v, "scope", query.Scope)
+	if errorResponse := v.ErrorResponse(); errorResponse != nil {
+		// The code stays specific to the scope, as in the authorization error of OAuth.
+		errorResponse.Code = utils.CodeInvalidScope
+		return nil, nil, errorResponse
+	}
+
+	// PKCE is required for every client, public clients have no other way to protect the code.
+	if query.CodeChallengeMethod != tokens.CodeChallengeMethodS256 || !tokens.ValidCodeChallenge(query.CodeChallenge) {
+		return nil, nil, utils.NewErrorResponse(utils.CodeInvalidCodeChallenge, "S256 code challenge is required", http.StatusBadRequest)
+	}
+
+	return &client, scopes, nil
+}
+
+func (s *DefaultOAuthService) GetConsent(ctx context.Context, token tokens.Token, query models.AuthorizeQuery) (*models.Consent, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "OAuthService.GetConsent")
+	defer span.End()
+
+	client, scopes, errorResponse := s.validateAuthorization(ctx, query)
+	if errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	return &models.Consent{
+		Client:      *models.NewClientInfo(*client),
+		Scopes:      scopes,
+		RedirectURI: query.RedirectURI,
+	}, nil
+}
+
+func (s *DefaultOAuthService) Authorize(ctx context.Context, token tokens.Token, payload models.AuthorizePayload) (*models.AuthorizeResult, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "OAuthService.Authorize")
+	defer span.End()
+
+	userId, err := strconv.Atoi(token.Subject)
+	if err != nil {
+		return nil, utils.InvalidTokenErrorResponse()
+	}
+
+	_, scopes, errorResponse := s.validateAuthorization(ctx, payload.AuthorizeQuery)
+	if errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	params := url.Values{}
+	if payload.State != "" {
+		params.Set("state", payload.State)
+	}
+
+	if !payload.Approve {
+		params.Set("error", "access_denied")
+		return &models.AuthorizeResult{RedirectURI: addQuery(payload.RedirectURI, params)}, nil
+	}
+
+	code, codeHash, err := tokens.NewOpaqueToken()
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	err = s.oauthRepository.AddAuthorizationCode(
+		ctx,
+		codeHash,
+		models.AuthorizationCode{
+			ClientId:      payload.ClientId,
+			UserId:        userId,
+			RedirectURI:   payload.RedirectURI,
+			Scopes:        scopes,
+			CodeChallenge: payload.CodeChallenge,
+		},
+		time.Now().Add(authorizationCodeExpiration),
+	)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	params.Set("code", code)
+	return &models.AuthorizeResult{RedirectURI: addQuery(payload.RedirectURI, params)}, nil
+}
+
+// addQuery will add the params to the query of the uri keeping its existing params.
+func addQuery(uri string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(uri, "?") {
+		separator = "&"
+	}
+
+	return uri + separator + params.Encode()
+}
+
+func (s *DefaultOAuthService) Exchange(ctx context.Context, request models.OAuthTokenRequest) (*models.OAuthTokenResponse, *models.OAuthError) {
+	ctx, span := tracing.Start(ctx, "OAuthService.Exchange")
+	defer span.End()
+
+	client, oauthError := s.authenticateClient(ctx, request.ClientId, request.ClientSecret)
+	if oauthError != nil {
+		return nil, oauthError
+	}
+
+	switch request.GrantType {
+	case "authorization_code":
+		return s.exchangeCode(ctx, client, request)
+	case "refresh_token":
+		return s.exchangeRefreshToken(ctx, client, request)
+	default:
+		return nil, models.NewOAuthError("unsupported_grant_type", "", http.StatusBadRequest)
+	}
+}
+
+// authenticateClient will return the client if it exists and the secret matches for confidential clients.
+func (s *DefaultOAuthService) authenticateClient(ctx context.Context, clientId, clientSecret string) (*models.OAuthClient, *models.OAuthError) {
+	client, err := s.oauthRepository.GetClient(ctx, clientId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewOAuthError("invalid_client", "", http.StatusUnauthorized)
+	} else if err != nil {
+		return nil, oauthServerError(ctx, err)
+	}
+
+	if client.Confidential() {
+		hash := tokens.HashOpaqueToken(clientSecret)
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) != 1 {
+			return nil, models.NewOAuthError("invalid_client", "", http.StatusUnauthorized)
+		}
+	}
+
+	return &client, nil
+}
+
+// exchangeCode will consume the authorization code and issue tokens if the code verifier matches.
+func (s *DefaultOAuthService) exchangeCode(ctx context.Context, client *models.OAuthClient, request models.OAuthTokenRequest) (*models.OAuthTokenResponse, *models.OAuthError) {
+	// The code is consumed even if the request is invalid, so it can't be guessed with the verifier.
+	code, err := s.oauthRepository.ConsumeAuthorizationCode(ctx, tokens.HashOpaqueToken(request.Code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewOAuthError("invalid_grant", "Invalid or expired code", http.StatusBadRequest)
+	} else if err != nil {
+		return nil, oauthServerError(ctx, err)
+	}
+
+	if code.ClientId != client.Id || code.RedirectURI != request.RedirectURI {
+		return nil, models.NewOAuthError("invalid_grant", "Code was issued to another client or redirect uri", http.StatusBadRequest)
+	}
+
+	if !tokens.VerifyCodeChallenge(request.CodeVerifier, code.CodeChallenge) {
+		return nil, models.NewOAuthError("invalid_grant", "Invalid code verifier", http.StatusBadRequest)
+	}
+
+	return s.issueTokens(ctx, client, code.UserId, code.Scopes)
+}
+
+// exchangeRefreshToken will rotate the refresh token of the client keeping the granted scopes.
+func (s *DefaultOAuthService) exchangeRefreshToken(ctx context.Context, client *models.OAuthClient, request models.OAuthTokenRequest) (*models.OAuthTokenResponse, *models.OAuthError) {
+	claims, err := s.authenticator.VerifyToken(request.RefreshToken, tokens.RefreshTokenType)
+	if err != nil {
+		return nil, models.NewOAuthError("invalid_grant", "Invalid refresh token", http.StatusBadRequest)
+	}
+
+	tokenId, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, models.NewOAuthError("invalid_grant", "Invalid refresh token", http.StatusBadRequest)
+	}
+
+	userId, scopes, err := s.tokenRepository.CheckClientToken(ctx, tokenId, client.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewOAuthError("invalid_grant", "Invalid refresh token", http.StatusBadRequest)
+	} else if err != nil {
+		return nil, oauthServerError(ctx, err)
+	}
+
+	err = s.tokenRepository.DeleteToken(ctx, tokenId)
+	if err != nil {
+		return nil, oauthServerError(ctx, err)
+	}
+
+	return s.issueTokens(ctx, client, userId, scopes)
+}
+
+// issueTokens will create a refresh token of the client add it to the database and create an access token
+// limited to the scopes.
+func (s *DefaultOAuthService) issueTokens(ctx context.Context, client *models.OAuthClient, userId int, scopes []string) (*models.OAuthTokenResponse, *models.OAuthError) {
+	user, err := s.userRepository.GetUserById(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewOAuthError("invalid_grant", "", http.StatusBadRequest)
+	} else if err != nil {
+		return nil, oauthServerError(ctx, err)
+	}
+
+	if errorResponse := checkCanLogIn(user, s.unverifiedPolicy); errorResponse != nil {
+		return nil, models.NewOAuthError("invalid_grant", errorResponse.Message, http.StatusBadRequest)
+	}
+
+	tokenId := uuid.New()
+	tokenExp := time.Now().Add(clientRefreshTokenExpiration)
+	refreshToken, err := s.authenticator.CreateRefreshToken(tokenId, tokenExp)
+	if err != nil {
+		return nil, oauthServerError(ctx, err)
+	}
+
+	err = s.tokenRepository.AddClientToken(ctx, tokenId, tokenExp, user.Id, client.Id, scopes)
+	if err != nil {
+		return nil, oauthServerError(ctx, err)
+	}
+
+	// The role of the user is not given to clients, they can access only the tasks.
+	accessToken, err := s.authenticator.CreateAccessToken(
+		tokens.AccessClaims{
+			UserId:    user.Id,
+			SessionId: tokenId,
+			ReadOnly:  !user.EmailVerified && s.unverifiedPolicy == config.UnverifiedReadOnly,
+			ClientId:  client.Id,
+			Scopes:    scopes,
+		},
+		time.Now().Add(clientAccessTokenExpiration),
+	)
+	if err != nil {
+		return nil, oauthServerError(ctx, err)
+	}
+
+	return &models.OAuthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(clientAccessTokenExpiration.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}, nil
+}
+
+// oauthServerError will log the error and return the server_error of RFC 6749.
+func oauthServerError(ctx context.Context, err error) *models.OAuthError {
+	logging.FromContext(ctx).ErrorContext(ctx, "Internal server error", "error", err)
+	return models.NewOAuthError("server_error", "", http.StatusInternalServerError)
+}
+
+func NewDefaultOAuthService(
+	oauthRepository repositories.OAuthRepository,
+	tokenRepository repositories.TokenRepository,
+	userRepository repositories.UserRepository,
+	authenticator *tokens.JWTAuthenticator,
+	unverifiedPolicy config.UnverifiedPolicy,
+) *DefaultOAuthService {
+	return &DefaultOAuthService{
+		oauthRepository:  oauthRepository,
+		tokenRepository:  tokenRepository,
+		userRepository:   userRepository,
+		authenticator:    authenticator,
+		unverifiedPolicy: unverifiedPolicy,
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"github.com/google/uuid"
 	"net/http"
 	"server/auth/tokens"
+	"server/metrics"
 	"server/models"
 	"server/repositories"
+	"server/tracing"
 	"server/utils"
 	"strconv"
 )
@@ -19,11 +21,17 @@ type TaskService interface {
 	// AddTask will add a new task and return the created one with an id.
 	AddTask(ctx context.Context, token tokens.Token, taskPayload *models.NewTaskPayload) (*models.TaskPayload, *utils.ErrorResponse)
 
-	// UpdateTask will update an existing task.
-	UpdateTask(ctx context.Context, taskPayload *models.TaskPayload) *utils.ErrorResponse
+	// UpdateTask will update an existing task of the user. Tasks of other users are not found.
+	UpdateTask(ctx context.Context, token tokens.Token, taskPayload *models.TaskPayload) *utils.ErrorResponse
 
-	// DeleteTask will delete an existing task.
-	DeleteTask(ctx context.Context, taskId uuid.UUID) *utils.ErrorResponse
+	// DeleteTask will delete an existing task of the user. Tasks of other users are not found.
+	DeleteTask(ctx context.Context, token tokens.Token, taskId uuid.UUID) *utils.ErrorResponse
+
+	// GetPriorities will return all priorities a task can have.
+	GetPriorities(ctx context.Context) ([]string, *utils.ErrorResponse)
+
+	// AddPriority will add a new priority tasks can have.
+	AddPriority(ctx context.Context, payload models.PriorityPayload) *utils.ErrorResponse
 }
 
 // DefaultTaskService is default implementation of [TaskService]
@@ -32,6 +40,9 @@ type DefaultTaskService struct {
 }
 
 func (s *DefaultTaskService) GetTasks(ctx context.Context, token tokens.Token) ([]models.TaskPayload, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetTasks")
+	defer span.End()
+
 	userId, err := strconv.Atoi(token.Subject)
 	if err != nil {
 		return nil, utils.InvalidTokenErrorResponse()
@@ -39,20 +50,24 @@ func (s *DefaultTaskService) GetTasks(ctx context.Context, token tokens.Token) (
 
 	tasks, err := s.taskRepository.GetTasks(ctx, userId)
 	if err != nil {
-		return nil, utils.InternalServerErrorResponse()
+		return nil, utils.InternalError(ctx, err)
 	}
 
+	metrics.CountTaskOperation(metrics.TaskRead)
 	return tasks, nil
 }
 
 func (s *DefaultTaskService) AddTask(ctx context.Context, token tokens.Token, taskPayload *models.NewTaskPayload) (*models.TaskPayload, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "TaskService.AddTask")
+	defer span.End()
+
 	result, err := s.taskRepository.CheckPriority(ctx, taskPayload.Priority)
 	if err != nil {
-		return nil, utils.InternalServerErrorResponse()
+		return nil, utils.InternalError(ctx, err)
 	}
 
 	if !result {
-		return nil, utils.NewErrorResponse("Invalid priority", http.StatusBadRequest)
+		return nil, utils.NewErrorResponse(utils.CodeInvalidPriority, "Invalid priority", http.StatusBadRequest)
 	}
 
 	task := models.TaskPayload{
@@ -62,6 +77,7 @@ func (s *DefaultTaskService) AddTask(ctx context.Context, token tokens.Token, ta
 			Description: taskPayload.Description,
 			Priority:    taskPayload.Priority,
 			Date:        taskPayload.Date,
+			Done:        taskPayload.Done,
 		},
 	}
 
@@ -72,39 +88,85 @@ func (s *DefaultTaskService) AddTask(ctx context.Context, token tokens.Token, ta
 
 	err = s.taskRepository.AddTask(ctx, &task, userId)
 	if err != nil {
-		return nil, utils.InternalServerErrorResponse()
+		return nil, utils.InternalError(ctx, err)
 	}
 
+	metrics.CountTaskOperation(metrics.TaskAdd)
 	return &task, nil
 }
 
-func (s *DefaultTaskService) UpdateTask(ctx context.Context, taskPayload *models.TaskPayload) *utils.ErrorResponse {
+func (s *DefaultTaskService) UpdateTask(ctx context.Context, token tokens.Token, taskPayload *models.TaskPayload) *utils.ErrorResponse {
+	ctx, span := tracing.Start(ctx, "TaskService.UpdateTask")
+	defer span.End()
+
+	userId, err := strconv.Atoi(token.Subject)
+	if err != nil {
+		return utils.InvalidTokenErrorResponse()
+	}
+
 	result, err := s.taskRepository.CheckPriority(ctx, taskPayload.Priority)
 	if err != nil {
-		return utils.InternalServerErrorResponse()
+		return utils.InternalError(ctx, err)
 	}
 	if !result {
-		return utils.NewErrorResponse("Invalid priority", http.StatusBadRequest)
+		return utils.NewErrorResponse(utils.CodeInvalidPriority, "Invalid priority", http.StatusBadRequest)
 	}
 
-	result, err = s.taskRepository.UpdateTask(ctx, taskPayload)
+	result, err = s.taskRepository.UpdateTask(ctx, taskPayload, userId)
 	if err != nil {
-		return utils.InternalServerErrorResponse()
+		return utils.InternalError(ctx, err)
 	}
 	if !result {
-		return utils.NewErrorResponse("Task not found", http.StatusNotFound)
+		return utils.NewErrorResponse(utils.CodeTaskNotFound, "Task not found", http.StatusNotFound)
 	}
 
+	metrics.CountTaskOperation(metrics.TaskUpdate)
 	return nil
 }
 
-func (s *DefaultTaskService) DeleteTask(ctx context.Context, taskId uuid.UUID) *utils.ErrorResponse {
-	result, err := s.taskRepository.DeleteTask(ctx, taskId)
+func (s *DefaultTaskService) DeleteTask(ctx context.Context, token tokens.Token, taskId uuid.UUID) *utils.ErrorResponse {
+	ctx, span := tracing.Start(ctx, "TaskService.DeleteTask")
+	defer span.End()
+
+	userId, err := strconv.Atoi(token.Subject)
+	if err != nil {
+		return utils.InvalidTokenErrorResponse()
+	}
+
+	result, err := s.taskRepository.DeleteTask(ctx, taskId, userId)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+	if !result {
+		return utils.NewErrorResponse(utils.CodeTaskNotFound, "Task not found", http.StatusNotFound)
+	}
+
+	metrics.CountTaskOperation(metrics.TaskDelete)
+	return nil
+}
+
+func (s *DefaultTaskService) GetPriorities(ctx context.Context) ([]string, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetPriorities")
+	defer span.End()
+
+	priorities, err := s.taskRepository.GetPriorities(ctx)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	return priorities, nil
+}
+
+func (s *DefaultTaskService) AddPriority(ctx context.Context, payload models.PriorityPayload) *utils.ErrorResponse {
+	ctx, span := tracing.Start(ctx, "TaskService.AddPriority")
+	defer span.End()
+
+	result, err := s.taskRepository.AddPriority(ctx, payload.Priority)
 	if err != nil {
-		return utils.InternalServerErrorResponse()
+		return utils.InternalError(ctx, err)
 	}
 	if !result {
-		return utils.NewErrorResponse("Task not found", http.StatusNotFound)
+		return utils.NewErrorResponse(utils.CodePriorityExists, "Priority already exists", http.StatusConflict)
 	}
 
 	return nil
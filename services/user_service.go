@@ -1,16 +1,28 @@
 package services
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"net/http"
+	"server/audit"
 	"server/auth/passwords"
 	"server/auth/tokens"
+	"server/config"
+	"server/logging"
+	"server/mail"
 	"server/models"
+	"server/ratelimit"
 	"server/repositories"
+	"server/tracing"
 	"server/utils"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,112 +34,1058 @@ type UserService interface {
 	// Login will check used credentials and return group of token if user is authenticated.
 	Login(ctx context.Context, payload models.LoginPayload) (*models.TokenGroup, *utils.ErrorResponse)
 
+	// LoginWithIdentity will log in the user linked to the account of the identity provider.
+	// An unlinked account is linked to the user with the same verified email, or a new user is created.
+	LoginWithIdentity(ctx context.Context, identity models.Identity) (*models.TokenGroup, *utils.ErrorResponse)
+
 	// Refresh will check if the token is valid. If the token is valid
 	// it will be deleted and new refresh token and access token will be generated.
 	Refresh(ctx context.Context, token tokens.Token) (*models.TokenGroup, *utils.ErrorResponse)
+
+	// ForgotPassword will send a single-use reset token to the email of the user.
+	// It doesn't return an error if the email is not in use, so the response can't be used to find registered emails.
+	ForgotPassword(ctx context.Context, payload models.ForgotPasswordPayload) *utils.ErrorResponse
+
+	// ResetPassword will check the reset token and set the new password.
+	// All refresh tokens of the user are revoked.
+	ResetPassword(ctx context.Context, payload models.ResetPasswordPayload) *utils.ErrorResponse
+
+	// SetPassword will set the password of the user without the current password or a reset token.
+	// It is used by operators, for example when the user lost access to the email. All refresh tokens
+	// of the user are revoked.
+	SetPassword(ctx context.Context, userId int, password string) *utils.ErrorResponse
+
+	// CreateUser will register the user like [UserService.Register] and return the id of the user.
+	// It is used by operators. If verified is true the email is marked as verified and the
+	// verification email is not sent.
+	CreateUser(ctx context.Context, payload models.RegistrationsPayload, verified bool) (int, *utils.ErrorResponse)
+
+	// VerifyEmail will check the verification token and mark the email of the user as verified.
+	VerifyEmail(ctx context.Context, payload models.VerifyEmailPayload) *utils.ErrorResponse
+
+	// ResendVerification will send a new verification token if the email is registered and not verified.
+	// Like [UserService.ForgotPassword] it doesn't reveal if the email is in use.
+	ResendVerification(ctx context.Context, payload models.ResendVerificationPayload) *utils.ErrorResponse
+
+	// ChangePassword will check the current password of the user and set the new one.
+	// If requested all other sessions of the user are revoked.
+	ChangePassword(ctx context.Context, token tokens.Token, payload models.ChangePasswordPayload) *utils.ErrorResponse
+
+	// ChangeEmail will check the password of the user and change the email.
+	// The new email must be verified again.
+	ChangeEmail(ctx context.Context, token tokens.Token, payload models.ChangeEmailPayload) *utils.ErrorResponse
+
+	// ChangeUsername will change the username of the user.
+	ChangeUsername(ctx context.Context, token tokens.Token, payload models.ChangeUsernamePayload) *utils.ErrorResponse
+
+	// DeleteAccount will check the password of the user and schedule the account for deletion
+	// after the grace period. All sessions of the user are revoked.
+	DeleteAccount(ctx context.Context, token tokens.Token, payload models.DeleteAccountPayload) (*models.AccountDeletion, *utils.ErrorResponse)
+
+	// ExportData will return a zip archive with the profile, tasks and sessions of the user as JSON.
+	ExportData(ctx context.Context, token tokens.Token) ([]byte, *utils.ErrorResponse)
+
+	// GetActivity will return the security events of the user account, the latest first.
+	GetActivity(ctx context.Context, token tokens.Token, query models.ActivityQuery) ([]models.AuditEvent, *utils.ErrorResponse)
 }
 
 // DefaultUseService struct is the default implementation of [UserService].
 type DefaultUseService struct {
-	userRepository   repositories.UserRepository
-	tokensRepository repositories.TokenRepository
-	authenticator    *tokens.JWTAuthenticator
+	userRepository              repositories.UserRepository
+	tokensRepository            repositories.TokenRepository
+	passwordResetRepository     repositories.PasswordResetRepository
+	emailVerificationRepository repositories.EmailVerificationRepository
+	identityRepository          repositories.IdentityRepository
+	taskRepository              repositories.TaskRepository
+	auditRepository             repositories.AuditRepository
+	recorder                    audit.Recorder
+	mailer                      mail.Mailer
+	authenticator               *tokens.JWTAuthenticator
+	hasher                      *passwords.Hasher
+	passwordPolicy              *passwords.Policy
+	limiter                     ratelimit.Limiter
+	unverifiedPolicy            config.UnverifiedPolicy
+	deletionGracePeriod         time.Duration
 }
 
 func (s *DefaultUseService) Register(ctx context.Context, payload models.RegistrationsPayload) *utils.ErrorResponse {
+	ctx, span := tracing.Start(ctx, "UserService.Register")
+	defer span.End()
+
+	_, errorResponse := s.CreateUser(ctx, payload, false)
+	return errorResponse
+}
+
+func (s *DefaultUseService) CreateUser(ctx context.Context, payload models.RegistrationsPayload, verified bool) (userId int, errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	defer func() {
+		event := models.NewAuditEvent(models.AuditRegister, userId)
+		event.Identifier = payload.Email
+		s.record(ctx, event, errorResponse)
+	}()
+
 	result, err := s.userRepository.CheckIfEmailExists(ctx, payload.Email)
 	if err != nil {
-		return utils.InternalServerErrorResponse()
+		return 0, utils.InternalError(ctx, err)
 	}
 
 	if result {
-		return utils.NewErrorResponse("Email already in use", http.StatusConflict)
+		return 0, utils.NewErrorResponse(utils.CodeEmailTaken, "Email already in use", http.StatusConflict)
 	}
 
 	result, err = s.userRepository.CheckIfUsernameExists(ctx, payload.Username)
 	if err != nil {
-		return utils.InternalServerErrorResponse()
+		return 0, utils.InternalError(ctx, err)
 	}
 	if result {
-		return utils.NewErrorResponse("Username already in use", http.StatusConflict)
+		return 0, utils.NewErrorResponse(utils.CodeUsernameTaken, "Username already in use", http.StatusConflict)
+	}
+
+	if errorResponse := s.checkPasswordPolicy(ctx, "password", payload.Password, payload.Username, payload.Email); errorResponse != nil {
+		return 0, errorResponse
+	}
+
+	hash, err := s.hashPassword(ctx, payload.Password)
+	if err != nil {
+		return 0, utils.InternalError(ctx, err)
+	}
+
+	userId, err = s.userRepository.AddUser(ctx, payload.Email, payload.Username, hash)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return 0, duplicateErrorResponse(err)
+	} else if err != nil {
+		return 0, utils.InternalError(ctx, err)
+	}
+
+	// The operator vouches for the email, so no verification email is sent.
+	if verified {
+		err = s.userRepository.MarkEmailVerified(ctx, userId)
+		if err != nil {
+			return userId, utils.InternalError(ctx, err)
+		}
+		return userId, nil
+	}
+
+	// The user is already registered, so failing to send the email should not fail the registration.
+	// The user can request a new email with [UserService.ResendVerification].
+	err = s.sendVerificationEmail(ctx, models.User{Id: userId, Email: payload.Email, Username: payload.Username})
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error sending verification email", "error", err)
+	}
+
+	return userId, nil
+}
+
+// duplicateErrorResponse will return the conflict of the value taken after the check for its uniqueness.
+func duplicateErrorResponse(err error) *utils.ErrorResponse {
+	if errors.Is(err, repositories.ErrDuplicateUsername) {
+		return utils.NewErrorResponse(utils.CodeUsernameTaken, "Username already in use", http.StatusConflict)
+	}
+	return utils.NewErrorResponse(utils.CodeEmailTaken, "Email already in use", http.StatusConflict)
+}
+
+// record will record the security event. The event is failed if the error response is not nil.
+func (s *DefaultUseService) record(ctx context.Context, event models.AuditEvent, errorResponse *utils.ErrorResponse) {
+	if errorResponse != nil {
+		event.Outcome = models.AuditFailure
+		event.Reason = errorResponse.Message
+	}
+
+	s.recorder.Record(ctx, event)
+}
+
+// tokenUserId will return the id of the user that is the subject of the token or zero if it is invalid.
+func tokenUserId(token tokens.Token) int {
+	userId, _ := strconv.Atoi(token.Subject)
+	return userId
+}
+
+// checkPasswordPolicy will return error with all unmet requirements if the password doesn't meet the policy.
+// The requirements are reported as errors of the field.
+func (s *DefaultUseService) checkPasswordPolicy(ctx context.Context, field, password, username, email string) *utils.ErrorResponse {
+	violations, err := s.passwordPolicy.Check(password, username, email)
+	if err != nil {
+		return utils.InternalError(ctx, err)
 	}
 
-	hash, err := passwords.HashPassword(payload.Password)
+	if len(violations) > 0 {
+		errorResponse := utils.NewErrorResponse(utils.CodeWeakPassword, "Password does not meet the requirements", http.StatusBadRequest)
+		for _, violation := range violations {
+			errorResponse.Fields = append(errorResponse.Fields, utils.FieldError{Field: field, Code: utils.CodeWeakPassword, Message: violation})
+		}
+		return errorResponse
+	}
+
+	return nil
+}
+
+// sendVerificationEmail will create a new verification token for the user and send it to the user email.
+func (s *DefaultUseService) sendVerificationEmail(ctx context.Context, user models.User) error {
+	// Only the latest verification token of the user should be valid.
+	err := s.emailVerificationRepository.DeleteUserVerificationTokens(ctx, user.Id)
+	if err != nil {
+		return err
+	}
+
+	token, tokenHash, err := tokens.NewOpaqueToken()
 	if err != nil {
-		return utils.InternalServerErrorResponse()
+		return err
 	}
 
-	err = s.userRepository.AddUser(ctx, payload.Email, payload.Username, hash)
+	err = s.emailVerificationRepository.AddVerificationToken(ctx, tokenHash, time.Now().Add(time.Hour*24), user.Id)
 	if err != nil {
-		return utils.InternalServerErrorResponse()
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nUse the following token to verify your email: %s\nThe token is valid for 24 hours.",
+			user.Username,
+			token,
+		),
+	})
+}
+
+// checkCanLogIn will return error if the user is not allowed to log in because
+// the account is disabled or the email is not verified.
+func checkCanLogIn(user models.User, unverifiedPolicy config.UnverifiedPolicy) *utils.ErrorResponse {
+	if user.Disabled {
+		return utils.DisabledAccountErrorResponse()
+	}
+
+	if !user.EmailVerified && unverifiedPolicy == config.UnverifiedBlock {
+		return utils.UnverifiedEmailErrorResponse()
 	}
 
 	return nil
 }
 
 // createTokenGroup will create a refresh token add it to the database and create an access token.
-func (s *DefaultUseService) createTokenGroup(ctx context.Context, userId int) (*models.TokenGroup, *utils.ErrorResponse) {
+// If the user email is not verified the access token can be read only depending on the configuration.
+func (s *DefaultUseService) createTokenGroup(ctx context.Context, user models.User) (*models.TokenGroup, *utils.ErrorResponse) {
 	tokenId := uuid.New()
 	tokenExp := time.Now().Add(time.Hour * 24 * 7)
 	refreshToken, err := s.authenticator.CreateRefreshToken(tokenId, tokenExp)
 	if err != nil {
-		return nil, utils.InternalServerErrorResponse()
+		return nil, utils.InternalError(ctx, err)
 	}
 
-	err = s.tokensRepository.AddToken(ctx, tokenId, tokenExp, userId)
+	err = s.tokensRepository.AddToken(ctx, tokenId, tokenExp, user.Id)
 	if err != nil {
-		return nil, utils.InternalServerErrorResponse()
+		return nil, utils.InternalError(ctx, err)
 	}
 
-	accessToken, err := s.authenticator.CreateAccessToken(userId, time.Now().Add(time.Minute*10))
+	readOnly := !user.EmailVerified && s.unverifiedPolicy == config.UnverifiedReadOnly
+	accessToken, err := s.authenticator.CreateAccessToken(
+		tokens.AccessClaims{
+			UserId:    user.Id,
+			SessionId: tokenId,
+			Role:      string(user.Role),
+			ReadOnly:  readOnly,
+		},
+		time.Now().Add(time.Minute*10),
+	)
 	if err != nil {
-		return nil, utils.InternalServerErrorResponse()
+		return nil, utils.InternalError(ctx, err)
 	}
 
 	return models.NewTokenGroup(accessToken, refreshToken), nil
 }
 
-func (s *DefaultUseService) Login(ctx context.Context, payload models.LoginPayload) (*models.TokenGroup, *utils.ErrorResponse) {
-	user, err := s.userRepository.GetUserByEmail(ctx, payload.Email)
+func (s *DefaultUseService) Login(ctx context.Context, payload models.LoginPayload) (tokenGroup *models.TokenGroup, errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer span.End()
+
+	var user models.User
+	var err error
+	defer func() {
+		event := models.NewAuditEvent(models.AuditLogin, user.Id)
+		event.Identifier = payload.Identifier
+		s.record(ctx, event, errorResponse)
+	}()
+
+	if payload.IsEmail() {
+		user, err = s.userRepository.GetUserByEmail(ctx, payload.Identifier)
+	} else {
+		user, err = s.userRepository.GetUserByUsername(ctx, payload.Identifier)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	// The attempts are also limited per account, so the password of a user can't be guessed by spreading
+	// the attempts over many ips. The account is found by its id, so logging in by email and by username
+	// share the attempts. Unknown identifiers are limited too, otherwise the lockout would reveal the accounts.
+	accountKey := "login:account:" + strconv.Itoa(user.Id)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, utils.NewErrorResponse("Invalid credentials", http.StatusUnauthorized)
-	} else if err != nil {
-		return nil, utils.InternalServerErrorResponse()
+		accountKey = "login:unknown:" + strings.ToLower(strings.TrimSpace(payload.Identifier))
+	}
+	if errorResponse := s.checkRateLimit(ctx, accountKey); errorResponse != nil {
+		return nil, errorResponse
 	}
 
-	passwordsMatch := passwords.VerifyPassword(payload.Password, user.Password)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.hitRateLimit(ctx, accountKey)
+		return nil, utils.NewErrorResponse(utils.CodeInvalidCredentials, "Invalid credentials", http.StatusUnauthorized)
+	}
+
+	passwordsMatch, rehash := s.verifyPassword(ctx, payload.Password, user.Password)
 	if !passwordsMatch {
-		return nil, utils.NewErrorResponse("Invalid credentials", http.StatusUnauthorized)
+		s.hitRateLimit(ctx, accountKey)
+		return nil, utils.NewErrorResponse(utils.CodeInvalidCredentials, "Invalid credentials", http.StatusUnauthorized)
+	}
+	s.resetRateLimit(ctx, accountKey)
+
+	// The password is known only now, so hashes with outdated algorithm or parameters are upgraded here.
+	// Failing to upgrade the hash should not fail the login.
+	if rehash {
+		s.rehashPassword(ctx, user.Id, payload.Password)
+	}
+
+	if errorResponse := checkCanLogIn(user, s.unverifiedPolicy); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	// Logging in during the grace period cancels the account deletion.
+	if user.DeleteAfter != nil {
+		err = s.userRepository.CancelDeletion(ctx, user.Id)
+		if err != nil {
+			return nil, utils.InternalError(ctx, err)
+		}
+	}
+
+	return s.createTokenGroup(ctx, user)
+}
+
+func (s *DefaultUseService) LoginWithIdentity(ctx context.Context, identity models.Identity) (tokenGroup *models.TokenGroup, errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.LoginWithIdentity")
+	defer span.End()
+
+	var userId int
+	defer func() {
+		event := models.NewAuditEvent(models.AuditLoginSSO, userId)
+		event.Identifier = identity.Email
+		s.record(ctx, event, errorResponse)
+	}()
+
+	userId, err := s.identityRepository.GetIdentityUser(ctx, identity.Issuer, identity.Subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		userId, errorResponse = s.linkIdentity(ctx, identity)
+		if errorResponse != nil {
+			return nil, errorResponse
+		}
+	} else if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	return s.loginIdentityUser(ctx, userId)
+}
+
+// loginIdentityUser will create tokens for the user logged in with an identity provider.
+func (s *DefaultUseService) loginIdentityUser(ctx context.Context, userId int) (*models.TokenGroup, *utils.ErrorResponse) {
+	user, err := s.userRepository.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	if errorResponse := checkCanLogIn(user, s.unverifiedPolicy); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	if user.DeleteAfter != nil {
+		err = s.userRepository.CancelDeletion(ctx, user.Id)
+		if err != nil {
+			return nil, utils.InternalError(ctx, err)
+		}
+	}
+
+	return s.createTokenGroup(ctx, user)
+}
+
+// linkIdentity will link the account of the identity provider to the user with the same email.
+// If there is no such user a new user without password is created. The email must be verified
+// by the provider, otherwise anyone could take over an account by registering its email at the provider.
+func (s *DefaultUseService) linkIdentity(ctx context.Context, identity models.Identity) (int, *utils.ErrorResponse) {
+	if identity.Email == "" || !identity.EmailVerified {
+		return 0, utils.NewErrorResponse(utils.CodeIdentityEmailUnverified, "Email of the identity provider account is not verified", http.StatusForbidden)
+	}
+
+	user, err := s.userRepository.GetUserByEmail(ctx, identity.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		username, errorResponse := s.availableUsername(ctx, identity)
+		if errorResponse != nil {
+			return 0, errorResponse
+		}
+
+		// Users created by the provider have no password. They can set one with the password reset.
+		user.Id, err = s.userRepository.AddUser(ctx, identity.Email, username, "")
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return 0, duplicateErrorResponse(err)
+		} else if err != nil {
+			return 0, utils.InternalError(ctx, err)
+		}
+	} else if err != nil {
+		return 0, utils.InternalError(ctx, err)
+	} else if !user.EmailVerified {
+		// The unverified account could be registered by anyone before the owner of the email, so its password
+		// and sessions are removed. Otherwise the account would stay accessible to them after the link.
+		// The owner can set a new password with the password reset.
+		err = s.userRepository.UpdatePassword(ctx, user.Id, "")
+		if err != nil {
+			return 0, utils.InternalError(ctx, err)
+		}
+
+		err = s.tokensRepository.DeleteUserTokens(ctx, user.Id)
+		if err != nil {
+			return 0, utils.InternalError(ctx, err)
+		}
+	}
+
+	if !user.EmailVerified {
+		err = s.userRepository.MarkEmailVerified(ctx, user.Id)
+		if err != nil {
+			return 0, utils.InternalError(ctx, err)
+		}
+	}
+
+	err = s.identityRepository.AddIdentity(ctx, identity.Issuer, identity.Subject, user.Id)
+	if err != nil {
+		return 0, utils.InternalError(ctx, err)
+	}
+
+	return user.Id, nil
+}
+
+// availableUsername will return a free username based on the preferred username or the email of the identity.
+// A number is appended if the username is already in use.
+func (s *DefaultUseService) availableUsername(ctx context.Context, identity models.Identity) (string, *utils.ErrorResponse) {
+	base := usernameBase(identity)
+	username := base
+	for i := 2; i < 100; i++ {
+		result, err := s.userRepository.CheckIfUsernameExists(ctx, username)
+		if err != nil {
+			return "", utils.InternalError(ctx, err)
+		}
+		if !result {
+			return username, nil
+		}
+
+		username = base + strconv.Itoa(i)
+	}
+
+	return "", utils.NewErrorResponse(utils.CodeUsernameTaken, "Username already in use", http.StatusConflict)
+}
+
+// usernameBase will return the preferred username of the identity, or the local part of its email if
+// the preferred username can't be used. The value is sanitized and truncated, so it is a valid username
+// even with the number appended by [DefaultUseService.availableUsername].
+func usernameBase(identity models.Identity) string {
+	local, _, _ := strings.Cut(identity.Email, "@")
+	for _, base := range []string{identity.PreferredUsername, local} {
+		base = strings.ReplaceAll(strings.Join(strings.Fields(base), ""), "@", "")
+		if runes := []rune(base); len(runes) > models.MaxUsernameLength-2 {
+			base = string(runes[:models.MaxUsernameLength-2])
+		}
+
+		if models.ValidUsername(base) {
+			return base
+		}
+	}
+
+	return "user"
+}
+
+// hashPassword will hash the password in its own span, as hashing is deliberately slow.
+func (s *DefaultUseService) hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "PasswordHasher.HashPassword")
+	defer span.End()
+
+	return s.hasher.HashPassword(password)
+}
+
+// verifyPassword will verify the password against the hash in its own span.
+func (s *DefaultUseService) verifyPassword(ctx context.Context, password, hash string) (bool, bool) {
+	_, span := tracing.Start(ctx, "PasswordHasher.VerifyPassword")
+	defer span.End()
+
+	return s.hasher.VerifyPassword(password, hash)
+}
+
+// rehashPassword will hash the password with the current algorithm and replace the stored hash of the user.
+func (s *DefaultUseService) rehashPassword(ctx context.Context, userId int, password string) {
+	hash, err := s.hashPassword(ctx, password)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error rehashing password", "error", err)
+		return
+	}
+
+	if err = s.userRepository.UpdatePassword(ctx, userId, hash); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error updating rehashed password", "error", err)
+	}
+}
+
+// checkRateLimit will return [utils.TooManyRequestsErrorResponse] if the key must wait before the next attempt.
+// If the limiter fails the attempt is allowed, so users can still log in.
+func (s *DefaultUseService) checkRateLimit(ctx context.Context, key string) *utils.ErrorResponse {
+	delay, err := s.limiter.Check(ctx, key)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error checking rate limit", "error", err)
+		return nil
 	}
+	if delay > 0 {
+		return utils.TooManyRequestsErrorResponse(delay)
+	}
+	return nil
+}
+
+// hitRateLimit will record a failed attempt of the key.
+func (s *DefaultUseService) hitRateLimit(ctx context.Context, key string) {
+	if _, err := s.limiter.Hit(ctx, key); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error recording rate limit attempt", "error", err)
+	}
+}
 
-	return s.createTokenGroup(ctx, user.Id)
+// resetRateLimit will forget the failed attempts of the key.
+func (s *DefaultUseService) resetRateLimit(ctx context.Context, key string) {
+	if err := s.limiter.Reset(ctx, key); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error resetting rate limit", "error", err)
+	}
 }
 
-func (s *DefaultUseService) Refresh(ctx context.Context, token tokens.Token) (*models.TokenGroup, *utils.ErrorResponse) {
+func (s *DefaultUseService) Refresh(ctx context.Context, token tokens.Token) (tokenGroup *models.TokenGroup, errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.Refresh")
+	defer span.End()
+
+	var userId int
+	defer func() {
+		s.record(ctx, models.NewAuditEvent(models.AuditRefresh, userId), errorResponse)
+	}()
+
 	tokenId, err := uuid.Parse(token.ID)
 	if err != nil {
 		return nil, utils.InvalidTokenErrorResponse()
 	}
 
-	userId, err := s.tokensRepository.CheckToken(ctx, tokenId)
+	userId, err = s.tokensRepository.CheckToken(ctx, tokenId)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, utils.InvalidTokenErrorResponse()
 	} else if err != nil {
-		return nil, utils.InternalServerErrorResponse()
+		return nil, utils.InternalError(ctx, err)
 	}
 
 	err = s.tokensRepository.DeleteToken(ctx, tokenId)
 	if err != nil {
-		return nil, utils.InternalServerErrorResponse()
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	user, err := s.userRepository.GetUserById(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, utils.InvalidTokenErrorResponse()
+	} else if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	if errorResponse := checkCanLogIn(user, s.unverifiedPolicy); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	return s.createTokenGroup(ctx, user)
+}
+
+func (s *DefaultUseService) ForgotPassword(ctx context.Context, payload models.ForgotPasswordPayload) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.ForgotPassword")
+	defer span.End()
+
+	var userId int
+	defer func() {
+		event := models.NewAuditEvent(models.AuditPasswordResetRequest, userId)
+		event.Identifier = payload.Email
+		s.record(ctx, event, errorResponse)
+	}()
+
+	user, err := s.userRepository.GetUserByEmail(ctx, payload.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+	userId = user.Id
+
+	// Only the latest reset token of the user should be valid.
+	err = s.passwordResetRepository.DeleteUserResetTokens(ctx, user.Id)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	token, tokenHash, err := tokens.NewOpaqueToken()
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	err = s.passwordResetRepository.AddResetToken(ctx, tokenHash, time.Now().Add(time.Hour), user.Id)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nUse the following token to reset your password: %s\nThe token is valid for one hour.",
+			user.Username,
+			token,
+		),
+	})
+	// The error is not returned, as only the registered emails would fail and the response would reveal them.
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error sending password reset email", "error", err)
+	}
+
+	return nil
+}
+
+func (s *DefaultUseService) ResetPassword(ctx context.Context, payload models.ResetPasswordPayload) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.ResetPassword")
+	defer span.End()
+
+	var userId int
+	defer func() {
+		s.record(ctx, models.NewAuditEvent(models.AuditPasswordReset, userId), errorResponse)
+	}()
+
+	tokenHash := tokens.HashOpaqueToken(payload.Token)
+	userId, err := s.passwordResetRepository.GetResetTokenUser(ctx, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return utils.NewErrorResponse(utils.CodeInvalidResetToken, "Invalid or expired reset token", http.StatusBadRequest)
+	} else if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	user, err := s.userRepository.GetUserById(ctx, userId)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	// The policy is checked before the token is consumed, so the user can try again with another password.
+	if errorResponse := s.checkPasswordPolicy(ctx, "password", payload.Password, user.Username, user.Email); errorResponse != nil {
+		return errorResponse
+	}
+
+	userId, err = s.passwordResetRepository.ConsumeResetToken(ctx, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return utils.NewErrorResponse(utils.CodeInvalidResetToken, "Invalid or expired reset token", http.StatusBadRequest)
+	} else if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	hash, err := s.hashPassword(ctx, payload.Password)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	err = s.userRepository.UpdatePassword(ctx, userId, hash)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	err = s.tokensRepository.DeleteUserTokens(ctx, userId)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+	s.record(ctx, models.NewAuditEvent(models.AuditTokensRevoked, userId), nil)
+
+	return nil
+}
+
+func (s *DefaultUseService) SetPassword(ctx context.Context, userId int, password string) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.SetPassword")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.NewAuditEvent(models.AuditPasswordReset, userId), errorResponse)
+	}()
+
+	user, err := s.userRepository.GetUserById(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return utils.NewErrorResponse(utils.CodeUserNotFound, "User not found", http.StatusNotFound)
+	} else if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	if errorResponse := s.checkPasswordPolicy(ctx, "password", password, user.Username, user.Email); errorResponse != nil {
+		return errorResponse
+	}
+
+	hash, err := s.hashPassword(ctx, password)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	err = s.userRepository.UpdatePassword(ctx, userId, hash)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	err = s.tokensRepository.DeleteUserTokens(ctx, userId)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+	s.record(ctx, models.NewAuditEvent(models.AuditTokensRevoked, userId), nil)
+
+	return nil
+}
+
+func (s *DefaultUseService) VerifyEmail(ctx context.Context, payload models.VerifyEmailPayload) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.VerifyEmail")
+	defer span.End()
+
+	var userId int
+	defer func() {
+		s.record(ctx, models.NewAuditEvent(models.AuditEmailVerify, userId), errorResponse)
+	}()
+
+	userId, err := s.emailVerificationRepository.ConsumeVerificationToken(ctx, tokens.HashOpaqueToken(payload.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return utils.NewErrorResponse(utils.CodeInvalidVerifyToken, "Invalid or expired verification token", http.StatusBadRequest)
+	} else if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	err = s.userRepository.MarkEmailVerified(ctx, userId)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	return nil
+}
+
+func (s *DefaultUseService) ResendVerification(ctx context.Context, payload models.ResendVerificationPayload) *utils.ErrorResponse {
+	ctx, span := tracing.Start(ctx, "UserService.ResendVerification")
+	defer span.End()
+
+	user, err := s.userRepository.GetUserByEmail(ctx, payload.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	// The error is not returned, as only the registered emails would fail and the response would reveal them.
+	err = s.sendVerificationEmail(ctx, user)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error sending verification email", "error", err)
+	}
+
+	return nil
+}
+
+// getTokenUser will fetch the user that is the subject of the access token.
+func (s *DefaultUseService) getTokenUser(ctx context.Context, token tokens.Token) (models.User, *utils.ErrorResponse) {
+	userId, err := strconv.Atoi(token.Subject)
+	if err != nil {
+		return models.User{}, utils.InvalidTokenErrorResponse()
+	}
+
+	user, err := s.userRepository.GetUserById(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, utils.InvalidTokenErrorResponse()
+	} else if err != nil {
+		return models.User{}, utils.InternalError(ctx, err)
+	}
+
+	return user, nil
+}
+
+func (s *DefaultUseService) ChangePassword(ctx context.Context, token tokens.Token, payload models.ChangePasswordPayload) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.NewAuditEvent(models.AuditPasswordChange, tokenUserId(token)), errorResponse)
+	}()
+
+	user, errorResponse := s.getTokenUser(ctx, token)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	if match, _ := s.verifyPassword(ctx, payload.CurrentPassword, user.Password); !match {
+		return utils.NewErrorResponse(utils.CodeInvalidCredentials, "Invalid credentials", http.StatusUnauthorized)
+	}
+
+	if errorResponse = s.checkPasswordPolicy(ctx, "new_password", payload.NewPassword, user.Username, user.Email); errorResponse != nil {
+		return errorResponse
+	}
+
+	hash, err := s.hashPassword(ctx, payload.NewPassword)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	err = s.userRepository.UpdatePassword(ctx, user.Id, hash)
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	if !payload.RevokeOtherSessions {
+		return nil
+	}
+
+	// Access tokens created before sessions were tracked don't have a session id,
+	// so every session has to be revoked.
+	sessionId, err := uuid.Parse(token.SessionId)
+	if err != nil {
+		err = s.tokensRepository.DeleteUserTokens(ctx, user.Id)
+	} else {
+		err = s.tokensRepository.DeleteUserTokensExcept(ctx, user.Id, sessionId)
+	}
+	if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+	s.record(ctx, models.NewAuditEvent(models.AuditTokensRevoked, user.Id), nil)
+
+	return nil
+}
+
+func (s *DefaultUseService) ChangeEmail(ctx context.Context, token tokens.Token, payload models.ChangeEmailPayload) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.ChangeEmail")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.NewAuditEvent(models.AuditEmailChange, tokenUserId(token)), errorResponse)
+	}()
+
+	user, errorResponse := s.getTokenUser(ctx, token)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	if match, _ := s.verifyPassword(ctx, payload.Password, user.Password); !match {
+		return utils.NewErrorResponse(utils.CodeInvalidCredentials, "Invalid credentials", http.StatusUnauthorized)
+	}
+
+	if payload.Email == user.Email {
+		return nil
+	}
+
+	// Changing only the case of the email doesn't need the uniqueness check, it would match the user itself.
+	if !strings.EqualFold(payload.Email, user.Email) {
+		result, err := s.userRepository.CheckIfEmailExists(ctx, payload.Email)
+		if err != nil {
+			return utils.InternalError(ctx, err)
+		}
+		if result {
+			return utils.NewErrorResponse(utils.CodeEmailTaken, "Email already in use", http.StatusConflict)
+		}
+	}
+
+	err := s.userRepository.UpdateEmail(ctx, user.Id, payload.Email)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return utils.NewErrorResponse(utils.CodeEmailTaken, "Email already in use", http.StatusConflict)
+	} else if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	// The address is the same when only its case changed, so it stays verified.
+	if strings.EqualFold(payload.Email, user.Email) {
+		return nil
+	}
+
+	user.Email = payload.Email
+	err = s.sendVerificationEmail(ctx, user)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Error sending verification email", "error", err)
+	}
+
+	return nil
+}
+
+func (s *DefaultUseService) ChangeUsername(ctx context.Context, token tokens.Token, payload models.ChangeUsernamePayload) (errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.ChangeUsername")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.NewAuditEvent(models.AuditUsernameChange, tokenUserId(token)), errorResponse)
+	}()
+
+	user, errorResponse := s.getTokenUser(ctx, token)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	if payload.Username == user.Username {
+		return nil
+	}
+
+	// Changing only the case of the username doesn't need the uniqueness check, it would match the user itself.
+	if !strings.EqualFold(payload.Username, user.Username) {
+		result, err := s.userRepository.CheckIfUsernameExists(ctx, payload.Username)
+		if err != nil {
+			return utils.InternalError(ctx, err)
+		}
+		if result {
+			return utils.NewErrorResponse(utils.CodeUsernameTaken, "Username already in use", http.StatusConflict)
+		}
+	}
+
+	err := s.userRepository.UpdateUsername(ctx, user.Id, payload.Username)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return utils.NewErrorResponse(utils.CodeUsernameTaken, "Username already in use", http.StatusConflict)
+	} else if err != nil {
+		return utils.InternalError(ctx, err)
+	}
+
+	return nil
+}
+
+func (s *DefaultUseService) DeleteAccount(ctx context.Context, token tokens.Token, payload models.DeleteAccountPayload) (deletion *models.AccountDeletion, errorResponse *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteAccount")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.NewAuditEvent(models.AuditAccountDeletion, tokenUserId(token)), errorResponse)
+	}()
+
+	user, errorResponse := s.getTokenUser(ctx, token)
+	if errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	if match, _ := s.verifyPassword(ctx, payload.Password, user.Password); !match {
+		return nil, utils.NewErrorResponse(utils.CodeInvalidCredentials, "Invalid credentials", http.StatusUnauthorized)
+	}
+
+	deleteAfter := time.Now().Add(s.deletionGracePeriod)
+	err := s.userRepository.ScheduleDeletion(ctx, user.Id, deleteAfter)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	err = s.tokensRepository.DeleteUserTokens(ctx, user.Id)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+	s.record(ctx, models.NewAuditEvent(models.AuditTokensRevoked, user.Id), nil)
+
+	return &models.AccountDeletion{DeleteAfter: models.ISOTime{Time: deleteAfter}}, nil
+}
+
+func (s *DefaultUseService) ExportData(ctx context.Context, token tokens.Token) ([]byte, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.ExportData")
+	defer span.End()
+
+	user, errorResponse := s.getTokenUser(ctx, token)
+	if errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	tasks, err := s.taskRepository.GetTasks(ctx, user.Id)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	sessions, err := s.tokensRepository.GetUserTokens(ctx, user.Id)
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", models.NewProfile(user)},
+		{"tasks.json", tasks},
+		{"sessions.json", sessions},
+	}
+
+	for _, file := range files {
+		if err = writeZipJSON(writer, file.name, file.data); err != nil {
+			return nil, utils.InternalError(ctx, err)
+		}
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (s *DefaultUseService) GetActivity(ctx context.Context, token tokens.Token, query models.ActivityQuery) ([]models.AuditEvent, *utils.ErrorResponse) {
+	ctx, span := tracing.Start(ctx, "UserService.GetActivity")
+	defer span.End()
+
+	userId, err := strconv.Atoi(token.Subject)
+	if err != nil {
+		return nil, utils.InvalidTokenErrorResponse()
+	}
+
+	events, err := s.auditRepository.GetEvents(ctx, models.AuditQuery{
+		UserId: userId,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	})
+	if err != nil {
+		return nil, utils.InternalError(ctx, err)
+	}
+
+	return events, nil
+}
+
+// writeZipJSON will add a file to the zip archive with the data encoded as JSON.
+func writeZipJSON(writer *zip.Writer, name string, data any) error {
+	file, err := writer.Create(name)
+	if err != nil {
+		return err
 	}
 
-	return s.createTokenGroup(ctx, userId)
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
 }
 
-func NewDefaultUserService(userRepository repositories.UserRepository, tokenRepository repositories.TokenRepository, authenticator *tokens.JWTAuthenticator) *DefaultUseService {
+func NewDefaultUserService(
+	userRepository repositories.UserRepository,
+	tokenRepository repositories.TokenRepository,
+	passwordResetRepository repositories.PasswordResetRepository,
+	emailVerificationRepository repositories.EmailVerificationRepository,
+	identityRepository repositories.IdentityRepository,
+	taskRepository repositories.TaskRepository,
+	auditRepository repositories.AuditRepository,
+	recorder audit.Recorder,
+	mailer mail.Mailer,
+	authenticator *tokens.JWTAuthenticator,
+	hasher *passwords.Hasher,
+	passwordPolicy *passwords.Policy,
+	limiter ratelimit.Limiter,
+	unverifiedPolicy config.UnverifiedPolicy,
+	deletionGracePeriod time.Duration,
+) *DefaultUseService {
 	return &DefaultUseService{
-		userRepository:   userRepository,
-		tokensRepository: tokenRepository,
-		authenticator:    authenticator,
+		userRepository:              userRepository,
+		tokensRepository:            tokenRepository,
+		passwordResetRepository:     passwordResetRepository,
+		emailVerificationRepository: emailVerificationRepository,
+		identityRepository:          identityRepository,
+		taskRepository:              taskRepository,
+		auditRepository:             auditRepository,
+		recorder:                    recorder,
+		mailer:                      mailer,
+		authenticator:               authenticator,
+		hasher:                      hasher,
+		passwordPolicy:              passwordPolicy,
+		limiter:                     limiter,
+		unverifiedPolicy:            unverifiedPolicy,
+		deletionGracePeriod:         deletionGracePeriod,
 	}
 }
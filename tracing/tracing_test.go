@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupRecorder will record the spans in memory for the test.
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+func TestMiddlewareContinuesIncomingTrace(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/tasks/:id", func(c *fiber.Ctx) error {
+		ctx, span := Start(c.UserContext(), "TaskService.GetTask")
+		defer span.End()
+
+		_, repositorySpan := Start(ctx, "TaskRepository.GetTask")
+		repositorySpan.End()
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/tasks/1", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := app.Test(request); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	repositorySpan, serviceSpan, serverSpan := spans[0], spans[1], spans[2]
+	if serverSpan.Name() != "GET /tasks/:id" {
+		t.Fatalf("Expected server span named by the route, got %q", serverSpan.Name())
+	}
+	if serverSpan.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("Expected the incoming trace to be continued, got trace %s", serverSpan.SpanContext().TraceID())
+	}
+	if serverSpan.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("Expected the incoming span to be the parent, got %s", serverSpan.Parent().SpanID())
+	}
+	if serviceSpan.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Fatal("Expected the service span to be a child of the server span")
+	}
+	if repositorySpan.Parent().SpanID() != serviceSpan.SpanContext().SpanID() {
+		t.Fatal("Expected the repository span to be a child of the service span")
+	}
+}
+
+func TestMiddlewareMarksServerErrors(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	})
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/fail", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Fatalf("Expected one failed span, got %v", spans)
+	}
+}
+
+func TestFailRecordsError(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	ctx, span := Start(t.Context(), "TaskService.GetTasks")
+	Fail(ctx, errors.New("connection refused"))
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error || spans[0].Status().Description != "connection refused" {
+		t.Fatalf("Expected one failed span, got %v", spans)
+	}
+	if events := spans[0].Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Fatalf("Expected the error to be recorded, got %v", events)
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+// Codes of the errors. They are part of the api, so clients can rely on them instead of
+// the messages. Existing codes must never be changed.
+const (
+	CodeValidationFailed        = "validation_failed"
+	CodeInternalError           = "internal_error"
+	CodeInvalidId               = "invalid_id"
+	CodeInvalidToken            = "invalid_token"
+	CodeInvalidCredentials      = "invalid_credentials"
+	CodeEmailUnverified         = "email_unverified"
+	CodeAccountDisabled         = "account_disabled"
+	CodeForbidden               = "forbidden"
+	CodeInsufficientScope       = "insufficient_scope"
+	CodeTooManyRequests         = "too_many_requests"
+	CodeEmailTaken              = "email_taken"
+	CodeUsernameTaken           = "username_taken"
+	CodeWeakPassword            = "weak_password"
+	CodeInvalidResetToken       = "invalid_reset_token"
+	CodeInvalidVerifyToken      = "invalid_verification_token"
+	CodeUserNotFound            = "user_not_found"
+	CodeCannotDisableSelf       = "cannot_disable_self"
+	CodeTaskNotFound            = "task_not_found"
+	CodeInvalidPriority         = "invalid_priority"
+	CodePriorityExists          = "priority_exists"
+	CodeClientNotFound          = "client_not_found"
+	CodeUnknownClient           = "unknown_client"
+	CodeInvalidRedirectURI      = "invalid_redirect_uri"
+	CodeUnsupportedResponse     = "unsupported_response_type"
+	CodeInvalidScope            = "invalid_scope"
+	CodeInvalidCodeChallenge    = "invalid_code_challenge"
+	CodeInvalidLoginState       = "invalid_login_state"
+	CodeLoginFailed             = "login_failed"
+	CodeIdentityEmailUnverified = "identity_email_unverified"
+)
+
+// Codes of the field errors.
+const (
+	FieldRequired        = "required"
+	FieldInvalidEmail    = "invalid_email"
+	FieldInvalidUsername = "invalid_username"
+	FieldInvalidURL      = "invalid_url"
+	FieldInvalidTime     = "invalid_time"
+	FieldInvalidEnum     = "invalid_enum"
+	FieldOutOfRange      = "out_of_range"
+)
@@ -1,21 +1,137 @@
 package utils
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"math"
+	"net/http"
+	"server/logging"
+	"server/tracing"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // ErrorResponse is the standard way of return error
 type ErrorResponse struct {
+	// Code is the stable machine readable code of the error, for example email_taken.
+	Code    string `json:"code,omitempty"`
 	Message string `json:"message"`
 	Status  int    `json:"status"`
+	// Fields holds the problems of the payload fields, for example every unmet password requirement.
+	// They are sent as errors of [Problem] and as the messages in errors of the legacy format.
+	Fields []FieldError `json:"-"`
+	// RequestId is the id of the request, so users can quote it when reporting the error.
+	RequestId string `json:"request_id,omitempty"`
+	// RetryAfter is how long the client must wait before the next attempt. It is sent in the Retry-After header.
+	RetryAfter time.Duration `json:"-"`
+}
+
+// FieldError struct holds the problem of a single payload field.
+type FieldError struct {
+	// Field is the json name of the field.
+	Field string `json:"field"`
+	// Code is the stable machine readable code of the problem, for example required.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problem is the error body in the application/problem+json format of RFC 7807.
+type Problem struct {
+	// Type is about:blank as the problems are identified by the code.
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestId string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// ProblemContentType is the content type of [Problem].
+const ProblemContentType = "application/problem+json"
+
+// ErrorFormat is a custom type for the format of error responses.
+type ErrorFormat string
+
+const (
+	// ProblemErrorFormat responds with [Problem].
+	ProblemErrorFormat ErrorFormat = "problem"
+	// LegacyErrorFormat responds with [ErrorResponse] for clients that don't support problems yet.
+	LegacyErrorFormat ErrorFormat = "legacy"
+)
+
+// errorFormat is the format used by [HandleErrorResponse]. It is set once at startup by [SetErrorFormat].
+// It is legacy by default, so existing clients keep receiving the body they parse.
+var errorFormat = LegacyErrorFormat
+
+// SetErrorFormat will set the default format of error responses. Clients can still ask for problems
+// with the Accept header in the legacy format.
+func SetErrorFormat(format ErrorFormat) {
+	errorFormat = format
 }
 
 // NewErrorResponse creates new instance of [ErrorResponse]
-func NewErrorResponse(message string, status int) *ErrorResponse {
+func NewErrorResponse(code, message string, status int) *ErrorResponse {
 	return &ErrorResponse{
+		Code:    code,
 		Message: message,
 		Status:  status,
 	}
 }
 
+// ValidationErrorResponse will return error with all problems of the payload fields.
+// The message is the message of the first problem, as in the legacy format only one was returned.
+func ValidationErrorResponse(fields []FieldError) *ErrorResponse {
+	errorResponse := NewErrorResponse(CodeValidationFailed, fields[0].Message, http.StatusBadRequest)
+	errorResponse.Fields = fields
+	return errorResponse
+}
+
+// FieldErrorResponse will return validation error with the single problem of the field.
+func FieldErrorResponse(field, code, message string) *ErrorResponse {
+	return ValidationErrorResponse([]FieldError{{Field: field, Code: code, Message: message}})
+}
+
+// Problem will convert the error to [Problem].
+func (e *ErrorResponse) Problem(instance string) *Problem {
+	return &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      e.Code,
+		RequestId: e.RequestId,
+		Errors:    e.Fields,
+	}
+}
+
+// Problems will return the messages of the field problems. A single problem is omitted when it is
+// already the message, as in the legacy format the errors were only listed when there was more than one.
+func (e *ErrorResponse) Problems() []string {
+	if len(e.Fields) == 1 && e.Fields[0].Message == e.Message {
+		return nil
+	}
+
+	problems := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		problems = append(problems, field.Message)
+	}
+	return problems
+}
+
+// MarshalJSON will encode the error in the legacy format, with the messages of [ErrorResponse.Problems] as errors.
+func (e *ErrorResponse) MarshalJSON() ([]byte, error) {
+	type legacy ErrorResponse
+	return json.Marshal(struct {
+		*legacy
+		Errors []string `json:"errors,omitempty"`
+	}{(*legacy)(e), e.Problems()})
+}
+
 // HandleErrorResponse will return true if the error is  not nil
 // and the function responded.
 func HandleErrorResponse(c *fiber.Ctx, error *ErrorResponse) bool {
@@ -23,18 +139,81 @@ func HandleErrorResponse(c *fiber.Ctx, error *ErrorResponse) bool {
 		return true
 	}
 
-	if err := c.Status(error.Status).JSON(error); err != nil {
+	error.RequestId = logging.RequestIdFromContext(c.UserContext())
+	if error.Code == "" {
+		error.Code = StatusCode(error.Status)
+	}
+
+	if error.RetryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(error.RetryAfter.Seconds()))))
+	}
+
+	var body any = error
+	contentType := fiber.MIMEApplicationJSON
+	if errorFormat == ProblemErrorFormat || strings.Contains(c.Get(fiber.HeaderAccept), ProblemContentType) {
+		body = error.Problem(c.Path())
+		contentType = ProblemContentType
+	}
+
+	if err := c.Status(error.Status).JSON(body, contentType); err != nil {
 		c.Status(fiber.StatusInternalServerError)
 	}
 	return false
 }
 
+// StatusCode will return the generic code of the status, for example not_found.
+func StatusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
 // InternalServerErrorResponse is the standard error return when there is a server error.
 func InternalServerErrorResponse() *ErrorResponse {
-	return NewErrorResponse("Internal Server Error", 500)
+	return NewErrorResponse(CodeInternalError, "Internal Server Error", 500)
+}
+
+// InternalError will log the error that caused the server error with the logger of the context, record it
+// in the span of the context and return [InternalServerErrorResponse]. The error itself is never sent to the client.
+func InternalError(ctx context.Context, err error) *ErrorResponse {
+	logging.FromContext(ctx).ErrorContext(ctx, "Internal server error", "error", err)
+	tracing.Fail(ctx, err)
+	return InternalServerErrorResponse()
+}
+
+// MalformedPayloadErrorResponse is the standard error returned when the payload can't be parsed,
+// for example a truncated json body or a value of a wrong type.
+func MalformedPayloadErrorResponse() *ErrorResponse {
+	return NewErrorResponse(CodeValidationFailed, "Malformed payload", 400)
 }
 
 // InvalidTokenErrorResponse is the standard error returned when the token is invalid.
 func InvalidTokenErrorResponse() *ErrorResponse {
-	return NewErrorResponse("Invalid Token", 401)
+	return NewErrorResponse(CodeInvalidToken, "Invalid Token", 401)
+}
+
+// UnverifiedEmailErrorResponse is the standard error returned when the user must verify the email first.
+func UnverifiedEmailErrorResponse() *ErrorResponse {
+	return NewErrorResponse(CodeEmailUnverified, "Email address is not verified", 403)
+}
+
+// TooManyRequestsErrorResponse is the standard error returned when the client is rate limited.
+// The client must wait retryAfter before the next attempt.
+func TooManyRequestsErrorResponse(retryAfter time.Duration) *ErrorResponse {
+	errorResponse := NewErrorResponse(CodeTooManyRequests, "Too many requests", 429)
+	errorResponse.RetryAfter = retryAfter
+	return errorResponse
+}
+
+// ForbiddenErrorResponse is the standard error returned when the user is not allowed to access the resource.
+func ForbiddenErrorResponse() *ErrorResponse {
+	return NewErrorResponse(CodeForbidden, "Forbidden", 403)
+}
+
+// DisabledAccountErrorResponse is the standard error returned when the account of the user is disabled.
+func DisabledAccountErrorResponse() *ErrorResponse {
+	return NewErrorResponse(CodeAccountDisabled, "Account is disabled", 403)
+}
+
+// InsufficientScopeErrorResponse is the standard error returned when the token of the client doesn't grant the scope.
+func InsufficientScopeErrorResponse() *ErrorResponse {
+	return NewErrorResponse(CodeInsufficientScope, "Insufficient scope", 403)
 }
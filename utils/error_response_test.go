@@ -0,0 +1,128 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"server/utils"
+	"testing"
+)
+
+func newApp() *fiber.App {
+	app := fiber.New()
+	app.Post("/register", func(c *fiber.Ctx) error {
+		utils.HandleErrorResponse(c, utils.FieldErrorResponse("email", utils.FieldRequired, "Email is required"))
+		return nil
+	})
+	return app
+}
+
+func request(t *testing.T, accept string) (*http.Response, map[string]any) {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, "/register", nil)
+	if accept != "" {
+		request.Header.Set(fiber.HeaderAccept, accept)
+	}
+	response, err := newApp().Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]any
+	if err = json.NewDecoder(response.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return response, body
+}
+
+func TestHandleErrorResponseProblem(t *testing.T) {
+	utils.SetErrorFormat(utils.ProblemErrorFormat)
+	t.Cleanup(func() { utils.SetErrorFormat(utils.LegacyErrorFormat) })
+
+	response, body := request(t, "")
+
+	if contentType := response.Header.Get(fiber.HeaderContentType); contentType != utils.ProblemContentType {
+		t.Fatalf("Expected problem content type, got %q", contentType)
+	}
+	if body["title"] != "Bad Request" || body["status"] != float64(400) || body["detail"] != "Email is required" {
+		t.Fatalf("Expected the problem members, got %v", body)
+	}
+	if body["code"] != utils.CodeValidationFailed || body["instance"] != "/register" {
+		t.Fatalf("Expected the code and instance, got %v", body)
+	}
+
+	fields, ok := body["errors"].([]any)
+	if !ok || len(fields) != 1 {
+		t.Fatalf("Expected one field error, got %v", body["errors"])
+	}
+	field := fields[0].(map[string]any)
+	if field["field"] != "email" || field["code"] != utils.FieldRequired {
+		t.Fatalf("Expected the email field error, got %v", field)
+	}
+}
+
+func TestHandleErrorResponseLegacy(t *testing.T) {
+	response, body := request(t, "")
+	if contentType := response.Header.Get(fiber.HeaderContentType); contentType != fiber.MIMEApplicationJSON {
+		t.Fatalf("Expected json content type, got %q", contentType)
+	}
+	if body["message"] != "Email is required" || body["status"] != float64(400) || body["code"] != utils.CodeValidationFailed {
+		t.Fatalf("Expected the legacy body, got %v", body)
+	}
+	if _, found := body["errors"]; found {
+		t.Fatalf("Expected no errors for a single problem, got %v", body["errors"])
+	}
+
+	// Clients can ask for problems before the legacy format is removed.
+	_, body = request(t, utils.ProblemContentType)
+	if body["detail"] != "Email is required" {
+		t.Fatalf("Expected problem for the accept header, got %v", body)
+	}
+}
+
+func TestErrorResponseLegacyErrors(t *testing.T) {
+	weakPassword := utils.NewErrorResponse(utils.CodeWeakPassword, "Password does not meet the requirements", http.StatusBadRequest)
+	weakPassword.Fields = []utils.FieldError{{Field: "password", Code: utils.CodeWeakPassword, Message: "Password is too short"}}
+
+	tests := []struct {
+		name     string
+		response *utils.ErrorResponse
+		expected []any
+	}{
+		{"single problem", utils.FieldErrorResponse("email", utils.FieldRequired, "Email is required"), nil},
+		{"single problem other than the message", weakPassword, []any{"Password is too short"}},
+		{"many problems", utils.ValidationErrorResponse([]utils.FieldError{
+			{Field: "email", Code: utils.FieldRequired, Message: "Email is required"},
+			{Field: "password", Code: utils.FieldRequired, Message: "Password is required"},
+		}), []any{"Email is required", "Password is required"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content, err := json.Marshal(test.response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var body map[string]any
+			if err = json.Unmarshal(content, &body); err != nil {
+				t.Fatal(err)
+			}
+			errors, _ := body["errors"].([]any)
+			if !reflect.DeepEqual(errors, test.expected) {
+				t.Fatalf("Expected errors %v, got %v", test.expected, body["errors"])
+			}
+			if body["message"] != test.response.Message || body["code"] != test.response.Code {
+				t.Fatalf("Expected the message and code, got %v", body)
+			}
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	if code := utils.StatusCode(http.StatusUnprocessableEntity); code != "unprocessable_entity" {
+		t.Fatalf("Expected unprocessable_entity, got %q", code)
+	}
+}
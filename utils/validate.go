@@ -1,11 +1,14 @@
 package utils
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+)
 
 // ValidatablePayload interface is used for payload that needs to be validated.
 type ValidatablePayload interface {
-	// ValidatePayload return error response if the payload is invalid with
-	// specified message. If the payload is valid it must return nil.
+	// ValidatePayload return error response with the problems of all invalid fields
+	// if the payload is invalid. If the payload is valid it must return nil.
 	ValidatePayload() *ErrorResponse
 }
 
@@ -15,3 +18,34 @@ func HandlePayload(c *fiber.Ctx, payload ValidatablePayload) bool {
 	errorResponse := payload.ValidatePayload()
 	return HandleErrorResponse(c, errorResponse)
 }
+
+// ParseBody will parse the body into the payload and validate it with [HandlePayload].
+// It returns false if the payload can't be used. If the payload is malformed or invalid the function
+// responded and the error is nil. Errors of fiber, like the unsupported content type, are returned
+// and must be returned by the handler.
+func ParseBody(c *fiber.Ctx, payload ValidatablePayload) (bool, error) {
+	if err := c.BodyParser(payload); err != nil {
+		return parseFailed(c, err)
+	}
+	return HandlePayload(c, payload), nil
+}
+
+// ParseQuery will parse the query into the payload and validate it the same way as [ParseBody].
+func ParseQuery(c *fiber.Ctx, payload ValidatablePayload) (bool, error) {
+	if err := c.QueryParser(payload); err != nil {
+		return parseFailed(c, err)
+	}
+	return HandlePayload(c, payload), nil
+}
+
+// parseFailed will respond with [MalformedPayloadErrorResponse] to the parse error. The payload sent by
+// the client is malformed, so it must not be handled as a server error.
+func parseFailed(c *fiber.Ctx, err error) (bool, error) {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return false, err
+	}
+
+	HandleErrorResponse(c, MalformedPayloadErrorResponse())
+	return false, nil
+}
@@ -0,0 +1,152 @@
+// Package validation provides declarative validation of payloads. The rules of a payload are
+// declared with [Validator] in its ValidatePayload method:
+//
+//	func (p *Payload) ValidatePayload() *utils.ErrorResponse {
+//		v := validation.New()
+//		v.Required("name", p.Name).MaxLength("name", p.Name, 100)
+//		v.Enum("state", p.State, "open", "closed")
+//		return v.ErrorResponse()
+//	}
+//
+// All fields are validated and every invalid field is reported. Only the first problem of a field
+// is reported, so the rules of a field should go from the most basic one.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"server/utils"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Validator collects the problems of the payload fields.
+type Validator struct {
+	errors []utils.FieldError
+	// invalid holds the fields that have a problem already.
+	invalid map[string]bool
+}
+
+// Check will add the problem to the field if the condition is false. It is used for rules that
+// are specific to the payload.
+func (v *Validator) Check(field string, ok bool, code, message string) *Validator {
+	if !ok && !v.invalid[field] {
+		v.invalid[field] = true
+		v.errors = append(v.errors, utils.FieldError{Field: field, Code: code, Message: message})
+	}
+	return v
+}
+
+// Required will check that the value is not empty.
+func (v *Validator) Required(field, value string) *Validator {
+	return v.Check(field, value != "", utils.FieldRequired, fmt.Sprintf("%s is required", label(field)))
+}
+
+// MinLength will check that the value has at least min characters.
+func (v *Validator) MinLength(field, value string, min int) *Validator {
+	return v.Check(field, utf8.RuneCountInString(value) >= min, utils.FieldOutOfRange,
+		fmt.Sprintf("%s must have at least %d characters", label(field), min))
+}
+
+// MaxLength will check that the value has at most max characters.
+func (v *Validator) MaxLength(field, value string, max int) *Validator {
+	return v.Check(field, utf8.RuneCountInString(value) <= max, utils.FieldOutOfRange,
+		fmt.Sprintf("%s must have at most %d characters", label(field), max))
+}
+
+// Enum will check that the value is one of the allowed values. Empty value is accepted,
+// use [Validator.Required] first if the field is required.
+func (v *Validator) Enum(field, value string, allowed ...string) *Validator {
+	return v.Check(field, value == "" || slices.Contains(allowed, value), utils.FieldInvalidEnum,
+		fmt.Sprintf("%s must be one of %s", label(field), strings.Join(allowed, ", ")))
+}
+
+// Range will check that the number is between min and max inclusive.
+func (v *Validator) Range(field string, value, min, max int) *Validator {
+	return v.Check(field, value >= min && value <= max, utils.FieldOutOfRange,
+		fmt.Sprintf("%s must be between %d and %d", label(field), min, max))
+}
+
+// Min will check that the number is at least min.
+func (v *Validator) Min(field string, value, min int) *Validator {
+	return v.Check(field, value >= min, utils.FieldOutOfRange,
+		fmt.Sprintf("%s must be at least %d", label(field), min))
+}
+
+// Email will check that the value is a single address as defined by RFC 5322. The display name, comments
+// and quoted local parts are not accepted, so the value is stored as the user will receive the emails.
+// The domain must contain a dot, as the addresses must be reachable from the internet.
+// Empty value is accepted.
+func (v *Validator) Email(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+
+	address, err := mail.ParseAddress(value)
+	valid := err == nil && address.Address == value
+	if valid {
+		domain := value[strings.LastIndex(value, "@")+1:]
+		valid = strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+	}
+	return v.Check(field, valid, utils.FieldInvalidEmail, fmt.Sprintf("%s must be a valid email address", label(field)))
+}
+
+// URL will check that the value is an absolute url with a host and without a fragment.
+// Empty value is accepted.
+func (v *Validator) URL(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+
+	parsed, err := url.Parse(value)
+	valid := err == nil && parsed.IsAbs() && parsed.Host != "" && parsed.Fragment == ""
+	return v.Check(field, valid, utils.FieldInvalidURL, fmt.Sprintf("%s must be an absolute url without a fragment", label(field)))
+}
+
+// Time will parse the RFC 3339 time into parsed. Empty value is accepted and leaves parsed zero.
+func (v *Validator) Time(field, value string, parsed *time.Time) *Validator {
+	if value == "" {
+		return v
+	}
+
+	var err error
+	*parsed, err = time.Parse(time.RFC3339, value)
+	return v.Check(field, err == nil, utils.FieldInvalidTime, fmt.Sprintf("%s must be RFC 3339 time", label(field)))
+}
+
+// TimeRange will check that the end is not before the start. The check is skipped if either is zero.
+// The problem is reported on the end field.
+func (v *Validator) TimeRange(startField string, start time.Time, endField string, end time.Time) *Validator {
+	return v.Check(endField, start.IsZero() || end.IsZero() || !end.Before(start), utils.FieldOutOfRange,
+		fmt.Sprintf("%s must not be before %s", label(endField), strings.ReplaceAll(startField, "_", " ")))
+}
+
+// Errors will return the problems of the fields in the order they were found.
+func (v *Validator) Errors() []utils.FieldError {
+	return v.errors
+}
+
+// ErrorResponse will return validation error with all problems or nil if the payload is valid.
+func (v *Validator) ErrorResponse() *utils.ErrorResponse {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return utils.ValidationErrorResponse(v.errors)
+}
+
+// label will convert the json name of the field to the name used in the messages, for example current_password
+// to Current password.
+func label(field string) string {
+	if field == "" {
+		return field
+	}
+	field = strings.ReplaceAll(field, "_", " ")
+	return strings.ToUpper(field[:1]) + field[1:]
+}
+
+func New() *Validator {
+	return &Validator{invalid: map[string]bool{}}
+}
@@ -0,0 +1,75 @@
+package validation
+
+import (
+	"server/utils"
+	"testing"
+	"time"
+)
+
+func TestValidatorAggregatesFields(t *testing.T) {
+	v := New()
+	v.Required("name", "").MaxLength("name", "", 10)
+	v.Required("state", "open").Enum("state", "open", "open", "closed")
+	v.Range("limit", 101, 1, 100)
+
+	errors := v.Errors()
+	if len(errors) != 2 {
+		t.Fatalf("Expected 2 field errors, got %v", errors)
+	}
+	if errors[0] != (utils.FieldError{Field: "name", Code: utils.FieldRequired, Message: "Name is required"}) {
+		t.Fatalf("Expected only the first problem of the name, got %v", errors[0])
+	}
+	if errors[1].Field != "limit" || errors[1].Code != utils.FieldOutOfRange {
+		t.Fatalf("Expected the limit to be out of range, got %v", errors[1])
+	}
+
+	response := v.ErrorResponse()
+	if response.Code != utils.CodeValidationFailed || len(response.Fields) != 2 || response.Message != "Name is required" {
+		t.Fatalf("Expected validation error with all fields, got %+v", response)
+	}
+
+	if response := New().Required("name", "task").ErrorResponse(); response != nil {
+		t.Fatalf("Expected no error for valid payload, got %+v", response)
+	}
+}
+
+func TestValidatorEmail(t *testing.T) {
+	tests := map[string]bool{
+		"user@example.com":          true,
+		"first.last+tag@mail.io":    true,
+		`"quoted user"@example.com`: false,
+		"":                          true,
+		"user example@example.com":  false,
+		"user@@example.com":         false,
+		"@example.com":              false,
+		"user@":                     false,
+		"user@localhost":            false,
+		"User <user@example.com>":   false,
+		"a@b.com, c@d.com":          false,
+	}
+
+	for email, valid := range tests {
+		if errors := New().Email("email", email).Errors(); (len(errors) == 0) != valid {
+			t.Fatalf("Expected %q valid to be %v, got %v", email, valid, errors)
+		}
+	}
+}
+
+func TestValidatorTime(t *testing.T) {
+	var from, to time.Time
+	v := New()
+	v.Time("from", "2024-01-02T00:00:00Z", &from)
+	v.Time("to", "2024-01-01T00:00:00Z", &to).TimeRange("from", from, "to", to)
+
+	errors := v.Errors()
+	if len(errors) != 1 || errors[0].Field != "to" || errors[0].Code != utils.FieldOutOfRange {
+		t.Fatalf("Expected the to field before from to be invalid, got %v", errors)
+	}
+	if !from.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected from to be parsed, got %v", from)
+	}
+
+	if errors := New().Time("from", "yesterday", &from).Errors(); len(errors) != 1 || errors[0].Code != utils.FieldInvalidTime {
+		t.Fatalf("Expected invalid time, got %v", errors)
+	}
+}